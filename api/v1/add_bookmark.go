@@ -0,0 +1,27 @@
+package v1
+
+// Endpoint: /api/v1/bookmarks/{snippet}
+// Method: PUT
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+func (a *Api) endpointAddBookmark(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.useCases.AddBookmark(GetCurrentUid(r), types.SnippetId(snippetId)); err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
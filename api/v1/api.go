@@ -8,6 +8,7 @@ import (
 	"github.com/mp-hl-2021/splinter/types"
 	"github.com/mp-hl-2021/splinter/usecases"
 	"net/http"
+	"strconv"
 )
 
 type Api struct {
@@ -41,17 +42,63 @@ func (a *Api) Router(router *mux.Router) {
 
 	router.Handle("/users/current", amw(http.HandlerFunc(a.endpointGetCurrentUser))).Methods(http.MethodGet)
 	router.Handle("/users/{user}", amw(http.HandlerFunc(a.endpointGetUser))).Methods(http.MethodGet)
+	router.Handle("/users/{user}/followers", amw(http.HandlerFunc(a.endpointGetFollowers))).Methods(http.MethodGet)
+	router.Handle("/users/{user}/followers", amw(http.HandlerFunc(a.endpointFollow))).Methods(http.MethodPost)
+	router.Handle("/users/{user}/followers", amw(http.HandlerFunc(a.endpointUnfollow))).Methods(http.MethodDelete)
+	router.Handle("/users/{user}/following", amw(http.HandlerFunc(a.endpointGetFollowing))).Methods(http.MethodGet)
 
+	router.Handle("/feed", amw(http.HandlerFunc(a.endpointGetFeed))).Methods(http.MethodGet)
 	router.Handle("/snippets", amw(http.HandlerFunc(a.endpointPostSnippet))).Methods(http.MethodPost)
 	router.Handle("/users/{user}/snippets", amw(http.HandlerFunc(a.endpointGetSnippetsByUser))).Methods(http.MethodGet)
+	router.Handle("/snippets/popular", amw(http.HandlerFunc(a.endpointGetPopularSnippets))).Methods(http.MethodGet)
+	router.Handle("/snippets/trending", amw(http.HandlerFunc(a.endpointGetTrendingSnippets))).Methods(http.MethodGet)
 	router.Handle("/snippets/language/{language}", amw(http.HandlerFunc(a.endpointGetSnippetsByLanguage))).Methods(http.MethodGet)
 	router.Handle("/snippets/{snippet}", amw(http.HandlerFunc(a.endpointGetSnippet))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}", amw(http.HandlerFunc(a.endpointEditSnippet))).Methods(http.MethodPatch)
 	router.Handle("/snippets/{snippet}", amw(http.HandlerFunc(a.endpointDeleteSnippet))).Methods(http.MethodDelete)
+	router.Handle("/snippets/{snippet}/fork", amw(http.HandlerFunc(a.endpointForkSnippet))).Methods(http.MethodPost)
+	router.Handle("/snippets/{snippet}/forks", amw(http.HandlerFunc(a.endpointGetForks))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}/files", amw(http.HandlerFunc(a.endpointGetFiles))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}/files/{file}", amw(http.HandlerFunc(a.endpointGetFile))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}/revisions", amw(http.HandlerFunc(a.endpointGetRevisions))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}/diff", amw(http.HandlerFunc(a.endpointDiffRevisions))).Methods(http.MethodGet)
+	router.Handle("/snippets/{snippet}/revisions/{revision}", amw(http.HandlerFunc(a.endpointGetRevision))).Methods(http.MethodGet)
 	router.Handle("/snippets/{snippet}/vote", amw(http.HandlerFunc(a.endpointVote))).Methods(http.MethodPost)
+	router.Handle("/snippets/{snippet}/reactions/{reaction}", amw(http.HandlerFunc(a.endpointAddReaction))).Methods(http.MethodPut)
+	router.Handle("/snippets/{snippet}/reactions/{reaction}", amw(http.HandlerFunc(a.endpointRemoveReaction))).Methods(http.MethodDelete)
+
+	router.Handle("/search", amw(http.HandlerFunc(a.endpointSearchSnippets))).Methods(http.MethodGet)
+	router.Handle("/search/code", amw(http.HandlerFunc(a.endpointSearchCode))).Methods(http.MethodGet)
+
+	router.Handle("/bookmarks", amw(http.HandlerFunc(a.endpointGetBookmarks))).Methods(http.MethodGet)
+	router.Handle("/bookmarks/{snippet}", amw(http.HandlerFunc(a.endpointAddBookmark))).Methods(http.MethodPut)
+	router.Handle("/bookmarks/{snippet}", amw(http.HandlerFunc(a.endpointRemoveBookmark))).Methods(http.MethodDelete)
+
+	router.Handle("/collections", amw(http.HandlerFunc(a.endpointCreateCollection))).Methods(http.MethodPost)
+	router.Handle("/users/{user}/collections", amw(http.HandlerFunc(a.endpointGetCollections))).Methods(http.MethodGet)
+	router.Handle("/collections/{collection}", amw(http.HandlerFunc(a.endpointGetCollection))).Methods(http.MethodGet)
+	router.Handle("/collections/{collection}", amw(http.HandlerFunc(a.endpointEditCollection))).Methods(http.MethodPatch)
+	router.Handle("/collections/{collection}", amw(http.HandlerFunc(a.endpointDeleteCollection))).Methods(http.MethodDelete)
+	router.Handle("/collections/{collection}/order", amw(http.HandlerFunc(a.endpointReorderCollection))).Methods(http.MethodPut)
+	router.Handle("/collections/{collection}/snippets/{snippet}", amw(http.HandlerFunc(a.endpointAddToCollection))).Methods(http.MethodPut)
+	router.Handle("/collections/{collection}/snippets/{snippet}", amw(http.HandlerFunc(a.endpointRemoveFromCollection))).Methods(http.MethodDelete)
+
+	router.Handle("/tags", amw(http.HandlerFunc(a.endpointGetPopularTags))).Methods(http.MethodGet)
+	router.Handle("/tags/{tag}/snippets", amw(http.HandlerFunc(a.endpointGetSnippetsByTag))).Methods(http.MethodGet)
+
+	router.Handle("/languages", amw(http.HandlerFunc(a.endpointGetLanguages))).Methods(http.MethodGet)
 
 	router.Handle("/snippets/{snippet}/comments", amw(http.HandlerFunc(a.endpointGetComments))).Methods(http.MethodGet)
 	router.Handle("/snippets/{snippet}/comments", amw(http.HandlerFunc(a.endpointPostComment))).Methods(http.MethodPost)
+	router.Handle("/snippets/{snippet}/comments/lines", amw(http.HandlerFunc(a.endpointGetLineComments))).Methods(http.MethodGet)
+	router.Handle("/comments/{comment}", amw(http.HandlerFunc(a.endpointEditComment))).Methods(http.MethodPatch)
 	router.Handle("/comments/{comment}", amw(http.HandlerFunc(a.endpointDeleteComment))).Methods(http.MethodDelete)
+	router.Handle("/comments/{comment}/history", amw(http.HandlerFunc(a.endpointGetCommentHistory))).Methods(http.MethodGet)
+	router.Handle("/comments/{comment}/vote", amw(http.HandlerFunc(a.endpointVoteComment))).Methods(http.MethodPost)
+	router.Handle("/comments/{comment}/reactions/{reaction}", amw(http.HandlerFunc(a.endpointAddCommentReaction))).Methods(http.MethodPut)
+	router.Handle("/comments/{comment}/reactions/{reaction}", amw(http.HandlerFunc(a.endpointRemoveCommentReaction))).Methods(http.MethodDelete)
+
+	router.Handle("/reactions", amw(http.HandlerFunc(a.endpointGetReactions))).Methods(http.MethodGet)
 }
 
 type errorResponse struct {
@@ -67,6 +114,40 @@ func WriteError(w http.ResponseWriter, err error, statusCode int) {
 	})
 }
 
+// StatusOf picks the status code for a use case error, errors without
+// a dedicated status code get fallback.
+func StatusOf(err error, fallback int) int {
+	if err == types.ErrSnippetExpired {
+		return http.StatusGone
+	}
+	if err == types.ErrDiffTooLarge {
+		return http.StatusRequestEntityTooLarge
+	}
+	return fallback
+}
+
 func GetCurrentUid(r *http.Request) types.UserId {
 	return context.Get(r, "uid").(types.UserId)
-}
\ No newline at end of file
+}
+
+func GetPage(r *http.Request) (types.Page, error) {
+	query := r.URL.Query()
+	cursor, err := types.ParseCursor(query.Get("cursor"))
+	if err != nil {
+		return types.Page{}, err
+	}
+
+	sort, err := types.ParseSortOrder(query.Get("sort"))
+	if err != nil {
+		return types.Page{}, err
+	}
+
+	var limit int
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			return types.Page{}, err
+		}
+	}
+
+	return types.Page{After: cursor, Limit: limit, Sort: sort}, nil
+}
@@ -0,0 +1,31 @@
+package v1
+
+// Endpoint: /api/v1/collections
+// Method: POST
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type createCollectionResponse struct {
+	Collection types.Collection
+}
+
+func (a *Api) endpointCreateCollection(w http.ResponseWriter, r *http.Request) {
+	var b types.NewCollection
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := a.useCases.CreateCollection(GetCurrentUid(r), b)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(createCollectionResponse{Collection: collection})
+}
@@ -0,0 +1,27 @@
+package v1
+
+// Endpoint: /api/v1/collections/{collection}
+// Method: DELETE
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+func (a *Api) endpointDeleteCollection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	collectionId, err := strconv.ParseUint(params["collection"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.useCases.DeleteCollection(GetCurrentUid(r), types.CollectionId(collectionId)); err != nil {
+		WriteError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
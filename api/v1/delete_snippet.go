@@ -19,7 +19,7 @@ func (a *Api) endpointDeleteSnippet(w http.ResponseWriter, r *http.Request) {
 	}
 	err = a.useCases.DeleteSnippet(GetCurrentUid(r), types.SnippetId(snippetId))
 	if err != nil {
-		WriteError(w, err, http.StatusNotFound)
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
 		return
 	}
 
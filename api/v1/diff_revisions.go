@@ -0,0 +1,84 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}/diff?file=&from=&to=&format=json|text|html
+// Method: GET
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/diff"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+var UnknownFormatErr = errors.New("unknown format")
+
+type diffRevisionsResponse struct {
+	File  string // Empty for the first file
+	From  int
+	To    int
+	Hunks []diff.Hunk
+}
+
+func (a *Api) endpointDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to int
+	if f := query.Get("from"); f != "" {
+		if from, err = strconv.Atoi(f); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if t := query.Get("to"); t != "" {
+		if to, err = strconv.Atoi(t); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := query.Get("format")
+	if format == "" && r.Header.Get("Accept") == "text/x-diff" {
+		format = "text"
+	}
+	// Checked before diffing, which reads the snippet and so burns burn-after-read ones
+	switch format {
+	case "", "json", "text", "html":
+	default:
+		WriteError(w, UnknownFormatErr, http.StatusBadRequest)
+		return
+	}
+
+	d, err := a.useCases.DiffRevisions(GetCurrentUid(r), types.SnippetId(snippetId), query.Get("file"), from, to)
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	switch format {
+	case "", "json":
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(diffRevisionsResponse{File: d.File, From: d.From.Number, To: d.To.Number, Hunks: d.Hunks})
+	case "text":
+		w.Header().Set("Content-Type", "text/x-diff; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(diff.Unified(
+			fmt.Sprintf("a/snippet-%d@%d", snippetId, d.From.Number),
+			fmt.Sprintf("b/snippet-%d@%d", snippetId, d.To.Number),
+			d.Hunks,
+		)))
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(a.useCases.RenderSideBySideDiff(d)))
+	}
+}
@@ -0,0 +1,41 @@
+package v1
+
+import (
+	"github.com/gorilla/context"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"github.com/mp-hl-2021/splinter/usecases"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// diffCounter counts diffs, which read snippets and so burn burn-after-read ones.
+type diffCounter struct {
+	usecases.UserInterface
+	diffs int
+}
+
+func (d *diffCounter) DiffRevisions(current types.UserId, snippet types.SnippetId, file string, from, to int) (types.RevisionDiff, error) {
+	d.diffs++
+	return types.RevisionDiff{}, nil
+}
+
+func TestUnknownDiffFormatIsRejectedBeforeDiffing(t *testing.T) {
+	u := &diffCounter{}
+	a := NewApi(u, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/snippets/1/diff?format=bogus", nil)
+	r = mux.SetURLVars(r, map[string]string{"snippet": "1"})
+	context.Set(r, "uid", types.UserId(2))
+	defer context.Clear(r)
+	w := httptest.NewRecorder()
+	a.endpointDiffRevisions(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if u.diffs != 0 {
+		t.Errorf("the snippet was diffed %d times, want none", u.diffs)
+	}
+}
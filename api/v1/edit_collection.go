@@ -0,0 +1,40 @@
+package v1
+
+// Endpoint: /api/v1/collections/{collection}
+// Method: PATCH
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type editCollectionResponse struct {
+	Collection types.Collection
+}
+
+func (a *Api) endpointEditCollection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	collectionId, err := strconv.ParseUint(params["collection"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var b types.CollectionEdit
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := a.useCases.EditCollection(GetCurrentUid(r), types.CollectionId(collectionId), b)
+	if err != nil {
+		WriteError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(editCollectionResponse{Collection: collection})
+}
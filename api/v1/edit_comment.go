@@ -0,0 +1,43 @@
+package v1
+
+// Endpoint: /api/v1/comments/{comment}
+// Method: PATCH
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type editCommentBody struct {
+	Contents string
+}
+
+type editCommentResponse struct {
+	Comment types.Comment
+}
+
+func (a *Api) endpointEditComment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	commentId, err := strconv.ParseUint(params["comment"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	var b editCommentBody
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	comment, err := a.useCases.EditComment(GetCurrentUid(r), types.CommentId(commentId), b.Contents)
+	if err != nil {
+		WriteError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(editCommentResponse{Comment: comment})
+}
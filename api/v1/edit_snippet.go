@@ -0,0 +1,40 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}
+// Method: PATCH
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type editSnippetResponse struct {
+	Snippet types.Snippet
+}
+
+func (a *Api) endpointEditSnippet(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var b types.SnippetEdit
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := a.useCases.EditSnippet(GetCurrentUid(r), types.SnippetId(snippetId), b)
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusForbidden))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(editSnippetResponse{Snippet: snippet})
+}
@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}/fork
+// Method: POST
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type forkSnippetResponse struct {
+	Snippet types.Snippet
+}
+
+func (a *Api) endpointForkSnippet(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snippet, err := a.useCases.ForkSnippet(GetCurrentUid(r), types.SnippetId(snippetId))
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(forkSnippetResponse{Snippet: snippet})
+}
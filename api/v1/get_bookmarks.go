@@ -0,0 +1,25 @@
+package v1
+
+// Endpoint: /api/v1/bookmarks
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type getBookmarksResponse struct {
+	Snippets []types.Snippet
+}
+
+func (a *Api) endpointGetBookmarks(w http.ResponseWriter, r *http.Request) {
+	snippets, err := a.useCases.GetBookmarks(GetCurrentUid(r))
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getBookmarksResponse{Snippets: snippets})
+}
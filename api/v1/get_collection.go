@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/collections/{collection}
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getCollectionResponse struct {
+	Collection types.Collection
+}
+
+func (a *Api) endpointGetCollection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	collectionId, err := strconv.ParseUint(params["collection"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := a.useCases.GetCollection(GetCurrentUid(r), types.CollectionId(collectionId))
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getCollectionResponse{Collection: collection})
+}
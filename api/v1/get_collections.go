@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/users/{user}/collections
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getCollectionsResponse struct {
+	Collections []types.Collection
+}
+
+func (a *Api) endpointGetCollections(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userId, err := strconv.ParseUint(params["user"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collections, err := a.useCases.GetCollections(types.UserId(userId), GetCurrentUid(r))
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getCollectionsResponse{Collections: collections})
+}
@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/comments/{comment}/history
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getCommentHistoryResponse struct {
+	History []types.CommentRevision
+}
+
+func (a *Api) endpointGetCommentHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	commentId, err := strconv.ParseUint(params["comment"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	history, err := a.useCases.GetCommentHistory(GetCurrentUid(r), types.CommentId(commentId))
+	if err != nil {
+		WriteError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getCommentHistoryResponse{History: history})
+}
@@ -1,18 +1,22 @@
 package v1
 
-// Endpoint: /snippets/{snippet}/comments
+// Endpoint: /api/v1/snippets/{snippet}/comments?cursor=&limit=&sort=&view=tree|flat
 // Method: GET
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/mux"
 	"github.com/mp-hl-2021/splinter/types"
 	"net/http"
 	"strconv"
 )
 
+var InvalidViewErr = errors.New("invalid view")
+
 type getCommentsResponse struct {
-	Comments []types.Comment
+	Comments   []types.Comment
+	NextCursor string
 }
 
 func (a *Api) endpointGetComments(w http.ResponseWriter, r *http.Request) {
@@ -22,12 +26,39 @@ func (a *Api) endpointGetComments(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, err, http.StatusBadRequest)
 		return
 	}
-	comments, err := a.useCases.GetComments(types.SnippetId(snippetId))
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	comments, err := a.useCases.GetComments(GetCurrentUid(r), types.SnippetId(snippetId), page)
 	if err != nil {
-		WriteError(w, err, http.StatusNotFound)
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	switch r.URL.Query().Get("view") {
+	case "", "tree":
+	case "flat":
+		comments.Comments = flattenComments(comments.Comments)
+	default:
+		WriteError(w, InvalidViewErr, http.StatusBadRequest)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(getCommentsResponse{Comments: comments})
+	_ = json.NewEncoder(w).Encode(getCommentsResponse{Comments: comments.Comments, NextCursor: comments.NextCursor})
+}
+
+// flattenComments lists threads depth-first, clients indent comments by their Depth.
+func flattenComments(c []types.Comment) []types.Comment {
+	var res []types.Comment
+	for _, comment := range c {
+		replies := comment.Replies
+		comment.Replies = nil
+		res = append(res, comment)
+		res = append(res, flattenComments(replies)...)
+	}
+	return res
 }
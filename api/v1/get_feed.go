@@ -0,0 +1,32 @@
+package v1
+
+// Endpoint: /api/v1/feed?cursor=&limit=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type getFeedResponse struct {
+	Snippets   []types.Snippet
+	NextCursor string
+}
+
+func (a *Api) endpointGetFeed(w http.ResponseWriter, r *http.Request) {
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	feed, err := a.useCases.GetFeed(GetCurrentUid(r), page)
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getFeedResponse{Snippets: feed.Snippets, NextCursor: feed.NextCursor})
+}
@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}/files/{file}
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getFileResponse struct {
+	File types.File
+}
+
+func (a *Api) endpointGetFile(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	file, err := a.useCases.GetFile(GetCurrentUid(r), types.SnippetId(snippetId), params["file"])
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getFileResponse{File: file})
+}
@@ -0,0 +1,41 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}/forks?cursor=&limit=&sort=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getForksResponse struct {
+	Snippets   []types.Snippet
+	NextCursor string
+}
+
+func (a *Api) endpointGetForks(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	forks, err := a.useCases.GetForks(types.SnippetId(snippetId), GetCurrentUid(r), page)
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getForksResponse{Snippets: forks.Snippets, NextCursor: forks.NextCursor})
+}
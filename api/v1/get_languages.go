@@ -0,0 +1,25 @@
+package v1
+
+// Endpoint: /api/v1/languages
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type getLanguagesResponse struct {
+	Languages []types.LanguageCount
+}
+
+func (a *Api) endpointGetLanguages(w http.ResponseWriter, r *http.Request) {
+	languages, err := a.useCases.GetLanguages()
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getLanguagesResponse{Languages: languages})
+}
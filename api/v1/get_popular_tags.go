@@ -0,0 +1,35 @@
+package v1
+
+// Endpoint: /api/v1/tags?limit=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getPopularTagsResponse struct {
+	Tags []types.TagCount
+}
+
+func (a *Api) endpointGetPopularTags(w http.ResponseWriter, r *http.Request) {
+	var limit int
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	tags, err := a.useCases.GetPopularTags(limit)
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getPopularTagsResponse{Tags: tags})
+}
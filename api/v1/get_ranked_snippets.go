@@ -0,0 +1,97 @@
+package v1
+
+// Endpoint: /api/v1/snippets/popular?rank=&language=&window=&limit=
+// Endpoint: /api/v1/snippets/trending?rank=&language=&window=&limit=
+// Method: GET
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mp-hl-2021/splinter/types"
+	"github.com/mp-hl-2021/splinter/usecases"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	UnknownRankingErr = errors.New("unknown ranking")
+	InvalidWindowErr  = errors.New("invalid time window")
+)
+
+var namedWindows = map[string]time.Duration{
+	"all":   0,
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+	"year":  365 * 24 * time.Hour,
+}
+
+type getRankedSnippetsResponse struct {
+	Snippets []types.Snippet
+}
+
+func (a *Api) endpointGetPopularSnippets(w http.ResponseWriter, r *http.Request) {
+	a.getRankedSnippets(w, r, "wilson", "all")
+}
+
+func (a *Api) endpointGetTrendingSnippets(w http.ResponseWriter, r *http.Request) {
+	a.getRankedSnippets(w, r, "hot", "week")
+}
+
+func (a *Api) getRankedSnippets(w http.ResponseWriter, r *http.Request, defaultRanking, defaultWindow string) {
+	query := r.URL.Query()
+
+	ranking, ok := usecases.RankingStrategies[valueOr(query.Get("rank"), defaultRanking)]
+	if !ok {
+		WriteError(w, UnknownRankingErr, http.StatusBadRequest)
+		return
+	}
+
+	window, err := parseWindow(valueOr(query.Get("window"), defaultWindow))
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	snippets, err := a.useCases.GetRankedSnippets(GetCurrentUid(r), usecases.RankingQuery{
+		Ranking:  ranking,
+		Language: types.ProgrammingLanguage(query.Get("language")),
+		Window:   window,
+		Limit:    limit,
+	})
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getRankedSnippetsResponse{Snippets: snippets})
+}
+
+// parseWindow accepts either a named window or a Go duration such as "36h".
+func parseWindow(s string) (time.Duration, error) {
+	if d, ok := namedWindows[s]; ok {
+		return d, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, InvalidWindowErr
+	}
+	return d, nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,19 @@
+package v1
+
+// Endpoint: /api/v1/reactions
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type getReactionsResponse struct {
+	Reactions []types.Reaction
+}
+
+func (a *Api) endpointGetReactions(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getReactionsResponse{Reactions: a.useCases.GetReactions()})
+}
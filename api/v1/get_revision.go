@@ -0,0 +1,40 @@
+package v1
+
+// Endpoint: /api/v1/snippets/{snippet}/revisions/{revision}
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type getRevisionResponse struct {
+	Revision types.Revision
+}
+
+func (a *Api) endpointGetRevision(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	revision, err := strconv.Atoi(params["revision"])
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	rev, err := a.useCases.GetRevision(GetCurrentUid(r), types.SnippetId(snippetId), revision)
+	if err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getRevisionResponse{Revision: rev})
+}
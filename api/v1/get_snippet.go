@@ -25,7 +25,7 @@ func (a *Api) endpointGetSnippet(w http.ResponseWriter, r *http.Request) {
 
 	snippet, err := a.useCases.GetSnippet(GetCurrentUid(r), types.SnippetId(snippetId))
 	if err != nil {
-		WriteError(w, err, http.StatusNotFound)
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
 		return
 	}
 
@@ -1,6 +1,6 @@
 package v1
 
-// Endpoint: /api/v1/snippets/language/{language}
+// Endpoint: /api/v1/snippets/language/{language}?cursor=&limit=&sort=
 // Method: GET
 
 import (
@@ -11,19 +11,26 @@ import (
 )
 
 type getSnippetsByLanguageResponse struct {
-	Snippets []types.Snippet
+	Snippets   []types.Snippet
+	NextCursor string
 }
 
 func (a *Api) endpointGetSnippetsByLanguage(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	language := types.ProgrammingLanguage(params["language"])
 
-	snippets, err := a.useCases.GetSnippetsByLanguage(language, GetCurrentUid(r))
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snippets, err := a.useCases.GetSnippetsByLanguage(language, GetCurrentUid(r), page)
 	if err != nil {
 		WriteError(w, err, http.StatusNotFound)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(getSnippetsByLanguageResponse{Snippets: snippets})
+	_ = json.NewEncoder(w).Encode(getSnippetsByLanguageResponse{Snippets: snippets.Snippets, NextCursor: snippets.NextCursor})
 }
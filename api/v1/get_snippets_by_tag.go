@@ -0,0 +1,35 @@
+package v1
+
+// Endpoint: /api/v1/tags/{tag}/snippets?cursor=&limit=&sort=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+)
+
+type getSnippetsByTagResponse struct {
+	Snippets   []types.Snippet
+	NextCursor string
+}
+
+func (a *Api) endpointGetSnippetsByTag(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snippets, err := a.useCases.GetSnippetsByTag(params["tag"], GetCurrentUid(r), page)
+	if err != nil {
+		WriteError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(getSnippetsByTagResponse{Snippets: snippets.Snippets, NextCursor: snippets.NextCursor})
+}
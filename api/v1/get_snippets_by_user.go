@@ -1,6 +1,6 @@
 package v1
 
-// Endpoint: /api/v1/users/{user}/snippets
+// Endpoint: /api/v1/users/{user}/snippets?cursor=&limit=&sort=
 // Method: GET
 
 import (
@@ -12,7 +12,8 @@ import (
 )
 
 type getSnippetsByUserResponse struct {
-	Snippets []types.Snippet
+	Snippets   []types.Snippet
+	NextCursor string
 }
 
 func (a *Api) endpointGetSnippetsByUser(w http.ResponseWriter, r *http.Request) {
@@ -23,12 +24,18 @@ func (a *Api) endpointGetSnippetsByUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	snippets, err := a.useCases.GetSnippetsByUser(types.UserId(userId), GetCurrentUid(r))
+	page, err := GetPage(r)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	snippets, err := a.useCases.GetSnippetsByUser(types.UserId(userId), GetCurrentUid(r), page)
 	if err != nil {
 		WriteError(w, err, http.StatusNotFound)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(getSnippetsByUserResponse{Snippets: snippets})
+	_ = json.NewEncoder(w).Encode(getSnippetsByUserResponse{Snippets: snippets.Snippets, NextCursor: snippets.NextCursor})
 }
@@ -12,6 +12,9 @@ import (
 )
 
 type postCommentBody struct {
+	ParentId types.CommentId // Zero for top-level comments
+	File     int             // Number of the file Lines are in, zero for the first one
+	Lines    types.LineRange // Zero for comments on the whole snippet
 	Contents string
 }
 
@@ -32,9 +35,15 @@ func (a *Api) endpointPostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	Comment, err := a.useCases.PostComment(GetCurrentUid(r), b.Contents, types.SnippetId(snippetId))
+	Comment, err := a.useCases.PostComment(GetCurrentUid(r), types.NewComment{
+		Snippet:  types.SnippetId(snippetId),
+		ParentId: b.ParentId,
+		File:     b.File,
+		Lines:    b.Lines,
+		Contents: b.Contents,
+	})
 	if err != nil {
-		WriteError(w, err, http.StatusForbidden)
+		WriteError(w, err, StatusOf(err, http.StatusForbidden))
 		return
 	}
 
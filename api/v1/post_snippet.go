@@ -7,9 +7,24 @@ import (
 	"encoding/json"
 	"github.com/mp-hl-2021/splinter/types"
 	"net/http"
+	"time"
 )
 
 type postSnippetBody struct {
+	Title         string
+	Description   string
+	Tags          []string
+	Visibility    types.Visibility
+	TTL           int // Seconds until the snippet expires, zero to keep it forever
+	BurnAfterRead bool
+	Filename      string
+	Contents      string
+	Language      types.ProgrammingLanguage
+	Files         []postSnippetFile // Files of a multi-file snippet, instead of Filename, Contents and Language
+}
+
+type postSnippetFile struct {
+	Name     string
 	Contents string
 	Language types.ProgrammingLanguage
 }
@@ -25,7 +40,23 @@ func (a *Api) endpointPostSnippet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	snippet, err := a.useCases.PostSnippet(GetCurrentUid(r), b.Contents, b.Language)
+	var files []types.File
+	for _, f := range b.Files {
+		files = append(files, types.File{Name: f.Name, Contents: f.Contents, Language: f.Language})
+	}
+
+	snippet, err := a.useCases.PostSnippet(GetCurrentUid(r), types.NewSnippet{
+		Title:         b.Title,
+		Description:   b.Description,
+		Tags:          b.Tags,
+		Visibility:    b.Visibility,
+		TTL:           time.Duration(b.TTL) * time.Second,
+		BurnAfterRead: b.BurnAfterRead,
+		Filename:      b.Filename,
+		Contents:      b.Contents,
+		Language:      b.Language,
+		Files:         files,
+	})
 	if err != nil {
 		WriteError(w, err, http.StatusForbidden)
 		return
@@ -0,0 +1,32 @@
+package v1
+
+// Endpoint: /api/v1/collections/{collection}/snippets/{snippet}
+// Method: DELETE
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+func (a *Api) endpointRemoveFromCollection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	collectionId, err := strconv.ParseUint(params["collection"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+	snippetId, err := strconv.ParseUint(params["snippet"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.useCases.RemoveFromCollection(GetCurrentUid(r), types.CollectionId(collectionId), types.SnippetId(snippetId)); err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusForbidden))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
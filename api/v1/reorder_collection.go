@@ -0,0 +1,44 @@
+package v1
+
+// Endpoint: /api/v1/collections/{collection}/order
+// Method: PUT
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type reorderCollectionBody struct {
+	Snippets []types.SnippetId // Every snippet of the collection in the new order
+}
+
+type reorderCollectionResponse struct {
+	Collection types.Collection
+}
+
+func (a *Api) endpointReorderCollection(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	collectionId, err := strconv.ParseUint(params["collection"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var b reorderCollectionBody
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection, err := a.useCases.ReorderCollection(GetCurrentUid(r), types.CollectionId(collectionId), b.Snippets)
+	if err != nil {
+		WriteError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(reorderCollectionResponse{Collection: collection})
+}
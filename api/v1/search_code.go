@@ -0,0 +1,56 @@
+package v1
+
+// Endpoint: /api/v1/search/code?q=&mode=identifier|regexp&language=&author=&limit=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type searchCodeResponse struct {
+	Results []types.SearchResult
+}
+
+func (a *Api) endpointSearchCode(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	mode, err := types.ParseCodeSearchMode(query.Get("mode"))
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var author uint64
+	if s := query.Get("author"); s != "" {
+		if author, err = strconv.ParseUint(s, 10, 64); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := a.useCases.SearchCode(GetCurrentUid(r), types.CodeSearchQuery{
+		Mode:     mode,
+		Pattern:  query.Get("q"),
+		Language: types.ProgrammingLanguage(query.Get("language")),
+		Author:   types.UserId(author),
+		Limit:    limit,
+	})
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(searchCodeResponse{Results: results})
+}
@@ -0,0 +1,50 @@
+package v1
+
+// Endpoint: /api/v1/search?q=&language=&author=&limit=
+// Method: GET
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+type searchSnippetsResponse struct {
+	Results []types.SearchResult
+}
+
+func (a *Api) endpointSearchSnippets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var author uint64
+	var err error
+	if s := query.Get("author"); s != "" {
+		if author, err = strconv.ParseUint(s, 10, 64); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			WriteError(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := a.useCases.SearchSnippets(GetCurrentUid(r), types.SearchQuery{
+		Text:     query.Get("q"),
+		Language: types.ProgrammingLanguage(query.Get("language")),
+		Author:   types.UserId(author),
+		Limit:    limit,
+	})
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(searchSnippetsResponse{Results: results})
+}
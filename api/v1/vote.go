@@ -30,7 +30,7 @@ func (a *Api) endpointVote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := a.useCases.Vote(GetCurrentUid(r), types.SnippetId(snippetId), b.Vote); err != nil {
-		WriteError(w, err, http.StatusNotFound)
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
 		return
 	}
 
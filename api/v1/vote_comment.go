@@ -0,0 +1,34 @@
+package v1
+
+// Endpoint: /api/v1/comments/{comment}/vote
+// Method: POST
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/mp-hl-2021/splinter/types"
+	"net/http"
+	"strconv"
+)
+
+func (a *Api) endpointVoteComment(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	var b voteBody
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	commentId, err := strconv.ParseUint(params["comment"], 10, 64)
+	if err != nil {
+		WriteError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := a.useCases.VoteComment(GetCurrentUid(r), types.CommentId(commentId), b.Vote); err != nil {
+		WriteError(w, err, StatusOf(err, http.StatusNotFound))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
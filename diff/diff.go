@@ -0,0 +1,255 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+type Line struct {
+	Op   Op
+	Text string
+	Old  int // Line number in the old text starting from 1, 0 for inserted lines
+	New  int // Line number in the new text starting from 1, 0 for deleted lines
+}
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []Line
+}
+
+// SplitLines splits text into lines, a trailing newline doesn't start a new line.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines computes the shortest edit script turning a into b with the linear
+// space variant of the Myers O(ND) algorithm, which finds the middle snake of
+// the edit graph and recurses on both sides of it.
+func Lines(a, b []string) []Line {
+	size := (len(a)+len(b)+1)/2 + 2
+	d := differ{a: a, b: b, forward: make([]int, 2*size+1), backward: make([]int, 2*size+1)}
+	d.compare(0, len(a), 0, len(b))
+	return d.res
+}
+
+// differ holds the texts being compared, the edit script found so far and the
+// furthest reaching paths, reused by every middle snake search.
+type differ struct {
+	a, b              []string
+	res               []Line
+	forward, backward []int
+}
+
+// compare appends the edit script turning a[a0:a1] into b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	// Common prefix
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.equal(a0, b0)
+		a0++
+		b0++
+	}
+	// Common suffix, appended after the rest
+	end := 0
+	for a0 < a1-end && b0 < b1-end && d.a[a1-end-1] == d.b[b1-end-1] {
+		end++
+	}
+	a1 -= end
+	b1 -= end
+
+	switch {
+	case a0 == a1:
+		for j := b0; j < b1; j++ {
+			d.res = append(d.res, Line{Op: OpInsert, Text: d.b[j], New: j + 1})
+		}
+	case b0 == b1:
+		for i := a0; i < a1; i++ {
+			d.res = append(d.res, Line{Op: OpDelete, Text: d.a[i], Old: i + 1})
+		}
+	default:
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.compare(a0, x, b0, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.equal(x, y)
+		}
+		d.compare(u, a1, v, b1)
+	}
+
+	for i := 0; i < end; i++ {
+		d.equal(a1+i, b1+i)
+	}
+}
+
+func (d *differ) equal(i, j int) {
+	d.res = append(d.res, Line{Op: OpEqual, Text: d.a[i], Old: i + 1, New: j + 1})
+}
+
+// middleSnake finds the snake from (x, y) to (u, v) in the middle of a shortest
+// edit script turning a[a0:a1] into b[b0:b1] by searching from both ends at once.
+// Both ranges must be non-empty.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	f, b := d.forward, d.backward
+	f[off+1], b[off+1] = 0, 0
+
+	for step := 0; step <= max; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || k != step && f[off+k-1] < f[off+k+1] {
+				x = f[off+k+1]
+			} else {
+				x = f[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			f[off+k] = x
+			// Diagonal k going forward is diagonal delta-k going backward
+			if c := delta - k; odd && c >= -(step-1) && c <= step-1 && x+b[off+c] >= n {
+				return a0 + sx, b0 + sy, a0 + x, b0 + y
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || k != step && b[off+k-1] < b[off+k+1] {
+				x = b[off+k+1]
+			} else {
+				x = b[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			b[off+k] = x
+			if c := delta - k; !odd && c >= -step && c <= step && x+f[off+c] >= n {
+				return a1 - x, b1 - y, a1 - sx, b1 - sy
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
+
+// Hunks groups changed lines of an edit script into hunks
+// surrounded by up to context unchanged lines.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		for i < len(lines) && lines[i].Op == OpEqual {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(lines) {
+			if lines[end].Op != OpEqual {
+				end++
+				continue
+			}
+			j := end
+			for j < len(lines) && lines[j].Op == OpEqual {
+				j++
+			}
+			if j == len(lines) || j-end > 2*context {
+				end += context
+				if end > len(lines) {
+					end = len(lines)
+				}
+				break
+			}
+			end = j
+		}
+
+		hunks = append(hunks, makeHunk(lines, start, end))
+		i = end
+	}
+	return hunks
+}
+
+func makeHunk(lines []Line, start, end int) Hunk {
+	var h Hunk
+	for _, l := range lines[:start] {
+		if l.Op != OpInsert {
+			h.OldStart++
+		}
+		if l.Op != OpDelete {
+			h.NewStart++
+		}
+	}
+
+	h.Lines = lines[start:end]
+	for _, l := range h.Lines {
+		if l.Op != OpInsert {
+			h.OldLines++
+		}
+		if l.Op != OpDelete {
+			h.NewLines++
+		}
+	}
+
+	// An empty range starts at the line right before it, like in diff -u
+	if h.OldLines > 0 {
+		h.OldStart++
+	}
+	if h.NewLines > 0 {
+		h.NewStart++
+	}
+	return h
+}
+
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Unified renders hunks in the unified diff format.
+func Unified(oldName, newName string, hunks []Hunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks {
+		b.WriteString(h.Header())
+		b.WriteByte('\n')
+		for _, l := range h.Lines {
+			switch l.Op {
+			case OpEqual:
+				b.WriteByte(' ')
+			case OpInsert:
+				b.WriteByte('+')
+			case OpDelete:
+				b.WriteByte('-')
+			}
+			b.WriteString(l.Text)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
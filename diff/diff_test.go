@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func randomLines(r *rand.Rand) []string {
+	lines := make([]string, r.Intn(30))
+	for i := range lines {
+		lines[i] = strconv.Itoa(r.Intn(4))
+	}
+	return lines
+}
+
+func TestLinesIsShortestEditScript(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for c := 0; c < 5000; c++ {
+		a, b := randomLines(r), randomLines(r)
+		script := Lines(a, b)
+
+		var old, new []string
+		edits := 0
+		for _, l := range script {
+			if l.Op != OpInsert {
+				old = append(old, l.Text)
+				if l.Old != len(old) {
+					t.Fatalf("%v -> %v: line %+v is numbered wrong", a, b, l)
+				}
+			}
+			if l.Op != OpDelete {
+				new = append(new, l.Text)
+				if l.New != len(new) {
+					t.Fatalf("%v -> %v: line %+v is numbered wrong", a, b, l)
+				}
+			}
+			if l.Op != OpEqual {
+				edits++
+			}
+		}
+		if fmtLines(old) != fmtLines(a) || fmtLines(new) != fmtLines(b) {
+			t.Fatalf("%v -> %v: script %+v doesn't turn one into the other", a, b, script)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("%v -> %v: %d edits, the shortest script has %d", a, b, edits, want)
+		}
+	}
+}
+
+func fmtLines(lines []string) string {
+	s := ""
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	return s
+}
+
+func TestLinesOfDistinctTexts(t *testing.T) {
+	a, b := make([]string, 4000), make([]string, 4000)
+	for i := range a {
+		a[i], b[i] = "a"+strconv.Itoa(i), "b"+strconv.Itoa(i)
+	}
+	if got := len(Lines(a, b)); got != 8000 {
+		t.Fatalf("got %d lines, want 8000", got)
+	}
+}
@@ -0,0 +1,71 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// SideBySide renders hunks as a two-column HTML table. oldHTML and newHTML
+// hold ready-to-embed (e.g. syntax-highlighted) lines of both texts indexed
+// by line number minus one; lines they lack are rendered as escaped text.
+func SideBySide(hunks []Hunk, oldHTML, newHTML []string) string {
+	var b strings.Builder
+	b.WriteString("<table class=\"diff\">\n")
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "<tr class=\"hunk\"><td colspan=\"4\">%s</td></tr>\n", html.EscapeString(h.Header()))
+
+		for i := 0; i < len(h.Lines); {
+			if h.Lines[i].Op == OpEqual {
+				writeRow(&b, &h.Lines[i], &h.Lines[i], oldHTML, newHTML)
+				i++
+				continue
+			}
+
+			// Pair up deleted and inserted lines of a single change
+			var deleted, inserted []*Line
+			for ; i < len(h.Lines) && h.Lines[i].Op != OpEqual; i++ {
+				if h.Lines[i].Op == OpDelete {
+					deleted = append(deleted, &h.Lines[i])
+				} else {
+					inserted = append(inserted, &h.Lines[i])
+				}
+			}
+			for j := 0; j < len(deleted) || j < len(inserted); j++ {
+				var old, new *Line
+				if j < len(deleted) {
+					old = deleted[j]
+				}
+				if j < len(inserted) {
+					new = inserted[j]
+				}
+				writeRow(&b, old, new, oldHTML, newHTML)
+			}
+		}
+	}
+	b.WriteString("</table>\n")
+	return b.String()
+}
+
+func writeRow(b *strings.Builder, old, new *Line, oldHTML, newHTML []string) {
+	b.WriteString("<tr>")
+	if old != nil {
+		writeCell(b, old.Op, old.Old, old.Text, oldHTML)
+	} else {
+		b.WriteString("<td class=\"lineno\"></td><td class=\"empty\"></td>")
+	}
+	if new != nil {
+		writeCell(b, new.Op, new.New, new.Text, newHTML)
+	} else {
+		b.WriteString("<td class=\"lineno\"></td><td class=\"empty\"></td>")
+	}
+	b.WriteString("</tr>\n")
+}
+
+func writeCell(b *strings.Builder, op Op, number int, text string, rendered []string) {
+	line := html.EscapeString(text)
+	if number > 0 && number <= len(rendered) {
+		line = rendered[number-1]
+	}
+	fmt.Fprintf(b, "<td class=\"lineno\">%d</td><td class=\"%s\"><pre>%s</pre></td>", number, op, line)
+}
@@ -2,56 +2,96 @@ package highlighter
 
 import (
 	"errors"
+	"github.com/mp-hl-2021/splinter/languages"
 	"github.com/mp-hl-2021/splinter/types"
-	"io/ioutil"
 	"log"
-	"os"
-	"os/exec"
+	"strings"
+	"sync"
 )
 
 var ChannelIsFullErr = errors.New("channel is full")
 
 type Highlighter struct {
-	requests chan types.Snippet
+	requests chan job
 	storage  types.SnippetStorage
+	backend  Backend
+	// mu keeps concurrent posts from taking up room one another checked for
+	mu *sync.Mutex
 }
 
-func New(storage types.SnippetStorage, size int) Highlighter {
+// Backend turns code into HTML marked up with pygments CSS classes, so that
+// highlighting looks the same whichever backend did it.
+type Backend interface {
+	// Render highlights contents without the wrapping <div> and <pre>. Every line
+	// of the input is a line of the output, with spans closed before line breaks.
+	Render(contents string, language types.ProgrammingLanguage) (string, error)
+}
+
+// Backends are the backends to choose from by name
+var Backends = map[string]Backend{
+	"native":   Native{},
+	"pygments": Pygments{},
+}
+
+// job highlights a file of a snippet revision. File zero is the one in the
+// snippet's Contents, other files are numbered in order starting from 1.
+type job struct {
+	snippet  types.SnippetId
+	revision int
+	file     int
+	contents string
+	language types.ProgrammingLanguage
+}
+
+func New(storage types.SnippetStorage, size int, backend Backend) Highlighter {
 	return Highlighter{
-		make(chan types.Snippet, size),
+		make(chan job, size),
 		storage,
+		backend,
+		&sync.Mutex{},
 	}
 }
 
-func highlightSnippet(snippet *types.Snippet) (string, error) {
-	input, err := ioutil.TempFile("", "")
-	if err != nil {
-		return "", err
+// lexerOf maps languages missing from the registry to plain text.
+func lexerOf(language types.ProgrammingLanguage) types.ProgrammingLanguage {
+	if l, ok := languages.Lookup(string(language)); ok {
+		return l.Id
 	}
-	defer os.Remove(input.Name())
-	defer input.Close()
+	return languages.Text
+}
 
-	if _, err = input.WriteString(snippet.Contents); err != nil {
+// HighlightCode highlights contents as a standalone HTML block.
+func (h *Highlighter) HighlightCode(contents string, language types.ProgrammingLanguage) (string, error) {
+	out, err := h.backend.Render(contents, language)
+	if err != nil {
 		return "", err
 	}
+	return `<div class="highlight"><pre><span></span>` + out + "</pre></div>\n", nil
+}
 
-	cmd := exec.Command("pygmentize", "-l", string(snippet.Language), "-f", "html", input.Name())
-	out, err := cmd.Output()
+// HighlightLines highlights contents and splits the resulting HTML into lines
+// without the wrapping <div> and <pre>, for views that lay out lines themselves.
+func (h *Highlighter) HighlightLines(contents string, language types.ProgrammingLanguage) ([]string, error) {
+	out, err := h.backend.Render(contents, language)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(out), nil
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n"), nil
 }
 
 func (h *Highlighter) Run() {
 	for {
-		snippet := <-h.requests
-		hl, err := highlightSnippet(&snippet)
+		j := <-h.requests
+		hl, err := h.HighlightCode(j.contents, j.language)
 		if err != nil {
 			log.Printf("Highlight error: %e", err)
 			continue
 		}
-		err = h.storage.SetSnippetHighlight(snippet.Id, hl)
+		if j.file == 0 {
+			err = h.storage.SetSnippetHighlight(j.snippet, j.revision, hl)
+		} else {
+			err = h.storage.SetFileHighlight(j.snippet, j.revision, j.file, hl)
+		}
 		if err != nil {
 			log.Printf("Highlight error: %e", err)
 			continue
@@ -59,11 +99,30 @@ func (h *Highlighter) Run() {
 	}
 }
 
+// Post queues highlighting of every file of the snippet that isn't highlighted
+// yet, one job per file, so that workers highlight the files in parallel.
+// Snippets without Files filled only get their Contents highlighted.
+// Either every job is queued or, if they don't all fit, none is.
 func (h *Highlighter) Post(snippet types.Snippet) error {
-	select {
-	case h.requests <- snippet:
-		return nil
-	default:
+	files := snippet.Files
+	if len(files) == 0 {
+		files = []types.File{snippet.MainFile()}
+	}
+
+	var jobs []job
+	for i, f := range files {
+		if f.HighlightedContents == "" {
+			jobs = append(jobs, job{snippet.Id, snippet.Revision, i, f.Contents, f.Language})
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if cap(h.requests)-len(h.requests) < len(jobs) {
 		return ChannelIsFullErr
 	}
+	for _, j := range jobs {
+		h.requests <- j
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+package highlighter
+
+import (
+	"github.com/mp-hl-2021/splinter/storage"
+	"github.com/mp-hl-2021/splinter/types"
+	"testing"
+)
+
+func TestPostQueuesEveryFileOrNone(t *testing.T) {
+	snippet := types.Snippet{Id: 1, Revision: 1, Files: []types.File{
+		{Name: "a.go", Contents: "package a", Language: "go"},
+		{Name: "b.go", Contents: "package b", Language: "go", HighlightedContents: "<span>package b</span>"},
+		{Name: "c.go", Contents: "package c", Language: "go"},
+	}}
+
+	h := New(storage.NewMemory(), 1, Native{})
+	if err := h.Post(snippet); err != ChannelIsFullErr {
+		t.Errorf("posting two jobs to room for one fails with %v, want %v", err, ChannelIsFullErr)
+	}
+	if len(h.requests) != 0 {
+		t.Errorf("%d jobs queued after a failed post, want none", len(h.requests))
+	}
+
+	h = New(storage.NewMemory(), 2, Native{})
+	if err := h.Post(snippet); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.requests) != 2 {
+		t.Errorf("%d jobs queued, want one per file not highlighted yet", len(h.requests))
+	}
+}
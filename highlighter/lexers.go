@@ -0,0 +1,367 @@
+package highlighter
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"strings"
+)
+
+// words maps every space separated word in lists to its class. A word in
+// several lists would get one of the classes at random, words panics instead.
+func words(lists map[string]string) map[string]string {
+	res := make(map[string]string)
+	for class, list := range lists {
+		for _, w := range strings.Fields(list) {
+			if c, ok := res[w]; ok && c != class {
+				panic("highlighter: " + w + " is both " + c + " and " + class)
+			}
+			res[w] = class
+		}
+	}
+	return res
+}
+
+var (
+	cComments     = [][2]string{{"/*", "*/"}}
+	doubleQuoted  = quote{`"`, "s2", false}
+	singleQuoted  = quote{`'`, "s1", false}
+	plainStrings  = []quote{{`"`, "s", false}}
+	scriptStrings = []quote{doubleQuoted, singleQuoted}
+)
+
+const (
+	cKeywords = "break case continue default do else for goto if return sizeof switch while typedef " +
+		"auto const extern inline register restrict static volatile struct union enum"
+	cTypes = "char double float int long short signed unsigned void size_t bool _Bool " +
+		"int8_t int16_t int32_t int64_t uint8_t uint16_t uint32_t uint64_t"
+	javascriptKeywords = "break case catch continue debugger default delete do else finally for if in " +
+		"instanceof new return switch throw try typeof while with yield await async of"
+	javascriptDeclarations = "var let const function class extends static get set"
+	javascriptBuiltins     = "console window document Object Array String Number Boolean Math JSON " +
+		"Promise Map Set Symbol Error parseInt parseFloat require"
+)
+
+// lexers are the lexers of the registry's languages, html and xml have their
+// own, languages without one are rendered as plain text.
+var lexers = map[types.ProgrammingLanguage]*lexer{
+	"go": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{{`"`, "s", false}, {"`", "s", true}},
+		chars:         true,
+		words: words(map[string]string{
+			"kn": "package import",
+			"kd": "var func struct map chan type interface const",
+			"k":  "break case continue default defer else fallthrough for go goto if range return select switch",
+			"kt": "bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64 rune string " +
+				"uint uint8 uint16 uint32 uint64 uintptr any",
+			"kc": "true false nil iota",
+			"nb": "append cap close complex copy delete imag len make new panic print println real recover",
+		}),
+		definers: map[string]string{"func": "nf"},
+	},
+	"python": {
+		lineComments: []string{"#"},
+		quotes:       []quote{{`"""`, "s2", false}, {`'''`, "s1", false}, doubleQuoted, singleQuoted},
+		decorators:   true,
+		words: words(map[string]string{
+			"k": "as assert async await break class continue def del elif else except finally for from " +
+				"global if lambda nonlocal pass raise return try while with yield",
+			"ow": "and in is not or",
+			"kn": "import",
+			"kc": "True False None",
+			"nb": "print len range abs all any bool dict dir enumerate filter float format getattr hasattr " +
+				"int isinstance iter list map max min next object open ord chr repr reversed round set " +
+				"sorted str sum super tuple type zip",
+			"bp": "self cls",
+		}),
+		definers: map[string]string{"def": "nf", "class": "nc"},
+	},
+	"javascript": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{doubleQuoted, singleQuoted, {"`", "sb", false}},
+		words: words(map[string]string{
+			"k":  javascriptKeywords + " void",
+			"kd": javascriptDeclarations,
+			"kn": "import export from",
+			"kc": "true false null undefined NaN Infinity",
+			"nb": javascriptBuiltins,
+			"bp": "this super",
+		}),
+		definers: map[string]string{"function": "nf", "class": "nc"},
+	},
+	"typescript": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{doubleQuoted, singleQuoted, {"`", "sb", false}},
+		decorators:    true,
+		words: words(map[string]string{
+			"k": javascriptKeywords + " as keyof",
+			"kd": javascriptDeclarations + " interface type enum implements readonly declare abstract " +
+				"public private protected namespace",
+			"kn": "import export from",
+			"kt": "string number boolean any void never unknown object",
+			"kc": "true false null undefined NaN Infinity",
+			"nb": javascriptBuiltins,
+			"bp": "this super",
+		}),
+		definers: map[string]string{"function": "nf", "class": "nc", "interface": "nc", "type": "nc", "enum": "nc"},
+	},
+	"java": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        plainStrings,
+		chars:         true,
+		decorators:    true,
+		words: words(map[string]string{
+			"k": "abstract assert break case catch continue default do else finally for if instanceof new " +
+				"return switch synchronized throw throws try while",
+			"kd": "class interface enum extends implements final private protected public static transient " +
+				"volatile native strictfp var record",
+			"kn": "import package",
+			"kt": "boolean byte char double float int long short void",
+			"kc": "true false null",
+			"bp": "this super",
+		}),
+		definers: map[string]string{"class": "nc", "interface": "nc", "enum": "nc", "record": "nc"},
+	},
+	"c": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        plainStrings,
+		chars:         true,
+		preprocessor:  true,
+		words: words(map[string]string{
+			"k":  cKeywords,
+			"kt": cTypes,
+			"kc": "NULL true false",
+		}),
+	},
+	"cpp": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        plainStrings,
+		chars:         true,
+		preprocessor:  true,
+		words: words(map[string]string{
+			"k": cKeywords + " class namespace template typename new delete operator public private protected " +
+				"virtual override final friend try catch throw using explicit mutable noexcept constexpr " +
+				"decltype static_cast dynamic_cast reinterpret_cast const_cast",
+			"kt": cTypes + " wchar_t char16_t char32_t",
+			"kc": "NULL nullptr true false",
+			"bp": "this",
+		}),
+		definers: map[string]string{"class": "nc", "struct": "nc", "namespace": "nn"},
+	},
+	"csharp": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        plainStrings,
+		chars:         true,
+		preprocessor:  true,
+		words: words(map[string]string{
+			"k": "abstract as break case catch checked class const continue default delegate do else enum " +
+				"event explicit extern finally fixed for foreach goto if implicit in interface internal is " +
+				"lock namespace new operator out override params private protected public readonly ref " +
+				"return sealed sizeof stackalloc static struct switch throw try typeof unchecked unsafe " +
+				"using virtual volatile while async await var get set",
+			"kt": "bool byte char decimal double float int long object sbyte short string uint ulong ushort void dynamic",
+			"kc": "true false null",
+			"bp": "this base",
+		}),
+		definers: map[string]string{"class": "nc", "struct": "nc", "interface": "nc", "enum": "nc", "namespace": "nn"},
+	},
+	"rust": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        plainStrings,
+		chars:         true,
+		macros:        true,
+		words: words(map[string]string{
+			"k": "as async await break const continue crate dyn else enum extern fn for if impl in let loop " +
+				"match mod move mut pub ref return static struct super trait type unsafe use where while",
+			"kt": "bool char f32 f64 i8 i16 i32 i64 i128 isize str u8 u16 u32 u64 u128 usize String Vec " +
+				"Option Result Box",
+			"kc": "true false None Some Ok Err",
+			"bp": "self Self",
+		}),
+		definers: map[string]string{"fn": "nf", "struct": "nc", "enum": "nc", "trait": "nc"},
+	},
+	"ruby": {
+		lineComments: []string{"#"},
+		quotes:       scriptStrings,
+		symbols:      true,
+		words: words(map[string]string{
+			"k": "alias and begin break case class def defined do else elsif end ensure for if in module " +
+				"next not or redo rescue retry return super then undef unless until when while yield",
+			"kc": "true false nil",
+			"nb": "puts print require require_relative attr_accessor attr_reader attr_writer include extend " +
+				"raise lambda proc",
+			"bp": "self",
+		}),
+		definers: map[string]string{"def": "nf", "class": "nc", "module": "nn"},
+	},
+	"php": {
+		lineComments:  []string{"//", "#"},
+		blockComments: cComments,
+		quotes:        scriptStrings,
+		variables:     true,
+		marks:         []string{"<?php", "<?=", "?>"},
+		words: words(map[string]string{
+			"k": "abstract and as break case catch class clone const continue declare default do echo else " +
+				"elseif empty enddeclare endfor endforeach endif endswitch endwhile extends final finally fn " +
+				"for foreach function global goto if implements include include_once instanceof insteadof " +
+				"interface isset list namespace new or print private protected public require require_once " +
+				"return static switch throw trait try unset use var while xor yield",
+			"kc": "true false null TRUE FALSE NULL",
+		}),
+		definers: map[string]string{"function": "nf", "class": "nc", "interface": "nc", "trait": "nc"},
+	},
+	"bash": {
+		lineComments: []string{"#"},
+		quotes:       []quote{doubleQuoted, {`'`, "s1", true}, {"`", "sb", false}},
+		variables:    true,
+		words: words(map[string]string{
+			"k": "if then else elif fi case esac for while until do done in function select return break " +
+				"continue exit local export readonly declare unset shift source",
+			"nb": "echo printf cd pwd read test eval exec set trap alias grep sed awk cat",
+		}),
+		definers: map[string]string{"function": "nf"},
+	},
+	"haskell": {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"{-", "-}"}},
+		quotes:        plainStrings,
+		chars:         true,
+		words: words(map[string]string{
+			"k": "case class data default deriving do else if import in infix infixl infixr instance let " +
+				"module newtype of then type where qualified as hiding",
+			"kt": "Int Integer Float Double Bool Char String Maybe Either IO",
+			"kc": "True False Nothing Just Left Right",
+		}),
+	},
+	"kotlin": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{{`"""`, "s", false}, {`"`, "s", false}},
+		chars:         true,
+		decorators:    true,
+		words: words(map[string]string{
+			"k": "as break continue do else for if in is return throw try when while override open data " +
+				"companion private public protected internal abstract final sealed enum lateinit suspend",
+			"kd": "val var fun class object interface typealias",
+			"kn": "package import",
+			"kc": "true false null",
+			"bp": "this super",
+		}),
+		definers: map[string]string{"fun": "nf", "class": "nc", "object": "nc", "interface": "nc"},
+	},
+	"swift": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{{`"""`, "s", false}, {`"`, "s", false}},
+		decorators:    true,
+		words: words(map[string]string{
+			"k": "associatedtype deinit extension fileprivate init inout internal open operator private " +
+				"public static subscript typealias break case continue default defer do else fallthrough " +
+				"for guard if in repeat return switch where while as catch is rethrows throws try",
+			"kd": "class enum func let protocol struct var",
+			"kn": "import",
+			"kt": "Int Double Float String Bool Character Array Dictionary Optional Void",
+			"kc": "true false nil",
+			"bp": "self Self super",
+		}),
+		definers: map[string]string{"func": "nf", "class": "nc", "struct": "nc", "protocol": "nc", "enum": "nc"},
+	},
+	"scala": {
+		lineComments:  []string{"//"},
+		blockComments: cComments,
+		quotes:        []quote{{`"""`, "s", false}, {`"`, "s", false}},
+		chars:         true,
+		decorators:    true,
+		words: words(map[string]string{
+			"k": "abstract case catch do else extends final finally for forSome if implicit lazy match new " +
+				"override private protected return sealed throw try while with yield",
+			"kd": "class def object trait type val var",
+			"kn": "import package",
+			"kt": "Int Long Double Float Boolean Char String Unit Any Nothing",
+			"kc": "true false null",
+			"bp": "this super",
+		}),
+		definers: map[string]string{"def": "nf", "class": "nc", "object": "nc", "trait": "nc"},
+	},
+	"sql": {
+		lineComments:    []string{"--"},
+		blockComments:   cComments,
+		quotes:          []quote{{`'`, "s1", false}, {`"`, "s2", false}},
+		caseInsensitive: true,
+		words: words(map[string]string{
+			"k": "select from where insert into values update set delete create table drop alter add index " +
+				"primary key foreign references join inner left right outer full on as and or not null is " +
+				"in exists between like group by order having limit offset distinct union all case when " +
+				"then else end default unique check constraint view with returning asc desc begin commit " +
+				"rollback transaction",
+			"kt": "int integer bigint smallint serial bigserial varchar char text boolean bool date time " +
+				"timestamp numeric decimal real float double precision uuid json jsonb",
+			"kc": "true false",
+		}),
+	},
+	"lua": {
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"--[[", "]]"}},
+		quotes:        scriptStrings,
+		words: words(map[string]string{
+			"k": "and break do else elseif end for function goto if in local not or repeat return then " +
+				"until while",
+			"kc": "true false nil",
+			"nb": "print pairs ipairs require type tostring tonumber table string math setmetatable " +
+				"getmetatable pcall error assert select",
+		}),
+		definers: map[string]string{"function": "nf"},
+	},
+	"perl": {
+		lineComments: []string{"#"},
+		quotes:       scriptStrings,
+		variables:    true,
+		words: words(map[string]string{
+			"k": "my our local sub use no package require if elsif else unless while until for foreach " +
+				"last next redo return do eval and or not",
+			"nb": "print printf say push pop shift unshift split join keys values defined die warn open " +
+				"close chomp",
+		}),
+		definers: map[string]string{"sub": "nf"},
+	},
+	"json": {
+		quotes: []quote{doubleQuoted},
+		keys:   true,
+		words:  words(map[string]string{"kc": "true false null"}),
+	},
+	"yaml": {
+		lineComments: []string{"#"},
+		quotes:       scriptStrings,
+		keys:         true,
+		words:        words(map[string]string{"kc": "true false null yes no"}),
+	},
+	"docker": {
+		lineComments:    []string{"#"},
+		quotes:          scriptStrings,
+		variables:       true,
+		caseInsensitive: true,
+		words: words(map[string]string{
+			"k": "from run cmd label expose env add copy entrypoint volume user workdir arg onbuild " +
+				"stopsignal healthcheck shell maintainer as",
+		}),
+	},
+	"make": {
+		lineComments: []string{"#"},
+		quotes:       scriptStrings,
+		variables:    true,
+		words: words(map[string]string{
+			"k": "ifeq ifneq ifdef ifndef else endif include define endef export override",
+		}),
+	},
+	"css": {
+		blockComments: cComments,
+		quotes:        scriptStrings,
+	},
+}
@@ -0,0 +1,439 @@
+package highlighter
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Native highlights code in-process with the lexers in this package. Its
+// output is laid out the way pygmentize lays out its own and uses the same
+// short CSS classes: k for keywords, s for strings, c1 for comments and so on.
+type Native struct{}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&#39;")
+
+func (Native) Render(contents string, language types.ProgrammingLanguage) (string, error) {
+	// Like pygmentize with stripnl=False, every line of contents is a line of
+	// the output, including blank ones at both ends, and the last line is ended
+	contents = strings.ReplaceAll(contents, "\r\n", "\n")
+	if !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+
+	var f formatter
+	id := lexerOf(language)
+	if id == "html" || id == "xml" {
+		lexMarkup(contents, f.emit)
+	} else if l, ok := lexers[id]; ok {
+		l.lex(contents, f.emit)
+	} else {
+		f.emit("", contents)
+	}
+	f.flush()
+	return f.out.String(), nil
+}
+
+// formatter joins adjacent tokens of the same class into one span and closes
+// spans before line breaks, so that every line stands on its own.
+type formatter struct {
+	out     strings.Builder
+	class   string
+	pending strings.Builder
+}
+
+func (f *formatter) emit(class, text string) {
+	if text == "" {
+		return
+	}
+	if class != f.class {
+		f.flush()
+		f.class = class
+	}
+	f.pending.WriteString(text)
+}
+
+func (f *formatter) flush() {
+	lines := strings.Split(f.pending.String(), "\n")
+	for i, line := range lines {
+		if i > 0 {
+			f.out.WriteByte('\n')
+		}
+		if line == "" {
+			continue
+		}
+		if f.class == "" {
+			f.out.WriteString(escaper.Replace(line))
+		} else {
+			f.out.WriteString(`<span class="` + f.class + `">` + escaper.Replace(line) + "</span>")
+		}
+	}
+	f.pending.Reset()
+}
+
+// quote delimits strings of the class, raw strings have no escapes.
+type quote struct {
+	delimiter string
+	class     string
+	raw       bool
+}
+
+// lexer is a table-driven lexer good enough for most C-like and scripting languages.
+type lexer struct {
+	lineComments    []string
+	blockComments   [][2]string // Checked before line comments, so that --[[ wins over --
+	quotes          []quote     // Longest delimiters first
+	chars           bool        // Single quotes delimit character literals
+	preprocessor    bool        // Lines starting with # are directives
+	variables       bool        // $name, ${name} and $(name)
+	decorators      bool        // @name
+	macros          bool        // name! calls a macro
+	symbols         bool        // :name is a symbol
+	keys            bool        // Strings and names followed by a colon are keys
+	marks           []string    // Delimiters of embedded code, such as <?php
+	caseInsensitive bool
+	words           map[string]string // Class of every keyword, builtin and constant
+	definers        map[string]string // Class of the name after a keyword, such as nf after func
+}
+
+const (
+	operatorChars    = "+-*/%=&|^!<>~?:"
+	punctuationChars = "()[]{},;."
+)
+
+func (l *lexer) lex(src string, emit func(class, text string)) {
+	definer := ""
+	lineStart := true
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		r, size := utf8.DecodeRuneInString(rest)
+
+		if unicode.IsSpace(r) {
+			j := i
+			for j < len(src) {
+				r, size := utf8.DecodeRuneInString(src[j:])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				if r == '\n' {
+					lineStart = true
+				}
+				j += size
+			}
+			emit("w", src[i:j])
+			i = j
+			continue
+		}
+
+		class, n := l.token(src, i, lineStart)
+		if n == 0 {
+			class, n = "", size
+		}
+		text := src[i : i+n]
+
+		if class == "n" && definer != "" {
+			class = definer
+		}
+		definer = ""
+		if class != "n" {
+			key := text
+			if l.caseInsensitive {
+				key = strings.ToLower(key)
+			}
+			definer = l.definers[key]
+		}
+
+		emit(class, text)
+		i += n
+		lineStart = false
+	}
+}
+
+// token recognizes the token at src[i:], returning its class and length.
+func (l *lexer) token(src string, i int, lineStart bool) (string, int) {
+	rest := src[i:]
+	if i == 0 && strings.HasPrefix(rest, "#!") {
+		return "ch", lineLength(rest)
+	}
+	if l.preprocessor && lineStart && rest[0] == '#' {
+		return "cp", lineLength(rest)
+	}
+	for _, m := range l.marks {
+		if strings.HasPrefix(rest, m) {
+			return "cp", len(m)
+		}
+	}
+	for _, c := range l.blockComments {
+		if strings.HasPrefix(rest, c[0]) {
+			end := strings.Index(rest[len(c[0]):], c[1])
+			if end < 0 {
+				return "cm", len(rest)
+			}
+			return "cm", len(c[0]) + end + len(c[1])
+		}
+	}
+	for _, c := range l.lineComments {
+		if strings.HasPrefix(rest, c) {
+			return "c1", lineLength(rest)
+		}
+	}
+	for _, q := range l.quotes {
+		if strings.HasPrefix(rest, q.delimiter) {
+			n := quoted(rest, q)
+			if l.keys && followedByColon(rest[n:]) {
+				return "nt", n
+			}
+			return q.class, n
+		}
+	}
+	if l.chars && rest[0] == '\'' {
+		if n := charLiteral(rest); n > 0 {
+			return "sc", n
+		}
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+	switch {
+	case isDigit(rest[0]) || rest[0] == '.' && len(rest) > 1 && isDigit(rest[1]):
+		return number(rest)
+	case l.variables && rest[0] == '$' && len(rest) > 1:
+		if rest[1] == '{' || rest[1] == '(' {
+			end := strings.IndexByte(rest, map[byte]byte{'{': '}', '(': ')'}[rest[1]])
+			if end > 0 && !strings.Contains(rest[:end], "\n") {
+				return "nv", end + 1
+			}
+		}
+		if n := identifier(rest[1:]); n > 0 {
+			return "nv", 1 + n
+		}
+	case l.decorators && rest[0] == '@':
+		if n := identifier(rest[1:]); n > 0 {
+			return "nd", 1 + n
+		}
+	case l.symbols && rest[0] == ':' && len(rest) > 1 && (i == 0 || src[i-1] != ':'):
+		if n := identifier(rest[1:]); n > 0 {
+			return "ss", 1 + n
+		}
+	case r == '_' || unicode.IsLetter(r):
+		n := identifier(rest)
+		word := rest[:n]
+		if l.macros && strings.HasPrefix(rest[n:], "!") && !strings.HasPrefix(rest[n:], "!=") {
+			return "fm", n + 1
+		}
+		if l.caseInsensitive {
+			word = strings.ToLower(word)
+		}
+		if class, ok := l.words[word]; ok {
+			return class, n
+		}
+		if l.keys && followedByColon(rest[n:]) {
+			return "nt", n
+		}
+		return "n", n
+	}
+
+	if n := run(rest, operatorChars); n > 0 {
+		return "o", n
+	}
+	if n := run(rest, punctuationChars); n > 0 {
+		return "p", n
+	}
+	return "", 0
+}
+
+func lineLength(s string) int {
+	if n := strings.IndexByte(s, '\n'); n >= 0 {
+		return n
+	}
+	return len(s)
+}
+
+// quoted returns the length of the string literal at the start of s. Strings
+// with one-character delimiters that aren't closed end with the line.
+func quoted(s string, q quote) int {
+	d := q.delimiter
+	for i := len(d); i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && !q.raw:
+			i++
+		case strings.HasPrefix(s[i:], d):
+			return i + len(d)
+		case s[i] == '\n' && len(d) == 1 && d != "`":
+			return i
+		}
+	}
+	return len(s)
+}
+
+// charLiteral returns the length of a character literal such as 'a' or '\n'
+// at the start of s, zero if there is none.
+func charLiteral(s string) int {
+	i := 1
+	if i < len(s) && s[i] == '\\' {
+		i++
+		for i < len(s) && s[i] != '\'' && s[i] != '\n' && i < 12 {
+			i++
+		}
+	} else if i < len(s) {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	if i < len(s) && s[i] == '\'' {
+		return i + 1
+	}
+	return 0
+}
+
+// number returns the class and the length of the number at the start of s.
+func number(s string) (string, int) {
+	class := "mi"
+	i := 0
+	if len(s) > 1 && s[0] == '0' && strings.ContainsRune("xXbBoO", rune(s[1])) {
+		class = map[byte]string{'x': "mh", 'b': "mb", 'o': "mo"}[s[1]|0x20]
+		i = 2
+		for i < len(s) && (isHexDigit(s[i]) || s[i] == '_') {
+			i++
+		}
+	} else {
+	digits:
+		for i < len(s) {
+			switch {
+			case isDigit(s[i]) || s[i] == '_':
+			case s[i] == '.' && i+1 < len(s) && isDigit(s[i+1]):
+				class = "mf"
+			case (s[i] == 'e' || s[i] == 'E') && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '-' || s[i+1] == '+'):
+				class = "mf"
+				i++
+			default:
+				break digits
+			}
+			i++
+		}
+	}
+	// Suffixes such as 10u, 1.5f or 100L
+	for i < len(s) && (s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z') {
+		i++
+	}
+	return class, i
+}
+
+func identifier(s string) int {
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != '_' && !unicode.IsLetter(r) && !(i > 0 && unicode.IsDigit(r)) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+func followedByColon(s string) bool {
+	s = strings.TrimLeft(s, " \t")
+	return strings.HasPrefix(s, ":") && !strings.HasPrefix(s, "::")
+}
+
+func run(s string, chars string) int {
+	i := 0
+	for i < len(s) && strings.IndexByte(chars, s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// lexMarkup splits HTML and XML into tags, attributes, comments, entities and text.
+func lexMarkup(src string, emit func(class, text string)) {
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			n := strings.Index(rest, "-->")
+			if n < 0 {
+				n = len(rest)
+			} else {
+				n += len("-->")
+			}
+			emit("cm", rest[:n])
+			i += n
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			n := strings.IndexByte(rest, '>') + 1
+			if n == 0 {
+				n = len(rest)
+			}
+			emit("cp", rest[:n])
+			i += n
+		case rest[0] == '<' && len(rest) > 1 && (rest[1] == '/' || unicode.IsLetter(rune(rest[1]))):
+			i += lexTag(rest, emit)
+		case rest[0] == '&':
+			n := strings.IndexByte(rest, ';') + 1
+			if n > 1 && n < 12 && !strings.ContainsAny(rest[:n], " \n<") {
+				emit("ni", rest[:n])
+				i += n
+				continue
+			}
+			emit("", "&")
+			i++
+		default:
+			n := strings.IndexAny(rest, "<&")
+			if n < 0 {
+				n = len(rest)
+			} else if n == 0 {
+				n = 1
+			}
+			emit("", rest[:n])
+			i += n
+		}
+	}
+}
+
+// lexTag emits the tag at the start of s and returns its length.
+func lexTag(s string, emit func(class, text string)) int {
+	i := 1
+	if s[i] == '/' {
+		i++
+	}
+	emit("p", s[:i])
+	n := i + run(s[i:], "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_:.")
+	emit("nt", s[i:n])
+	i = n
+
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '>':
+			emit("p", ">")
+			return i + 1
+		case strings.HasPrefix(s[i:], "/>"):
+			emit("p", "/>")
+			return i + 2
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			n := i + run(s[i:], " \t\n\r")
+			emit("w", s[i:n])
+			i = n
+		case c == '=':
+			emit("o", "=")
+			i++
+		case c == '"' || c == '\'':
+			n := i + quoted(s[i:], quote{delimiter: string(c), raw: true})
+			emit("s", s[i:n])
+			i = n
+		default:
+			n := i + 1
+			for n < len(s) && !strings.ContainsRune(" \t\n\r=>/\"'", rune(s[n])) {
+				n++
+			}
+			emit("na", s[i:n])
+			i = n
+		}
+	}
+	return i
+}
@@ -0,0 +1,157 @@
+package highlighter
+
+import (
+	"github.com/mp-hl-2021/splinter/languages"
+	"html"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+// The golden files in testdata are samples of every registered language,
+// each with <language>.pygments.html next to it, the output of
+//
+//	pygmentize -l <language> -f html -O nowrap,stripnl=False <sample>
+//
+// Regenerate them after changing the samples.
+
+var spanRe = regexp.MustCompile(`<span class="([\w-]*)[^"]*">([^<]*)</span>|[^<]+`)
+
+// classes maps every character of the rendered code to the CSS class it is
+// in, line by line.
+func classes(t *testing.T, rendered string) [][]string {
+	t.Helper()
+	var res [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(rendered, "\n"), "\n") {
+		var chars []string
+		for _, m := range spanRe.FindAllStringSubmatch(line, -1) {
+			class, text := m[1], m[2]
+			if !strings.HasPrefix(m[0], "<span") {
+				text = m[0]
+			}
+			for _, r := range html.UnescapeString(text) {
+				if unicode.IsSpace(r) {
+					// Lexers differ in whether spaces go with the tokens around them
+					chars = append(chars, "w")
+				} else {
+					chars = append(chars, class)
+				}
+			}
+		}
+		if got := strings.Join(spanRe.FindAllString(line, -1), ""); got != line {
+			t.Fatalf("%q is not made of spans and text", line)
+		}
+		res = append(res, chars)
+	}
+	return res
+}
+
+// tokenKind is the kind of token of a pygments short CSS class, k for
+// keywords of every sort, s for strings, c for comments and m for numbers.
+// Names, operators, punctuation and plain text are alike: lexers, pygments
+// ones included, tell them apart each in their own way.
+func tokenKind(class string) string {
+	switch {
+	case class == "" || class == "w" || class == "err":
+		return ""
+	case strings.IndexByte("nop", class[0]) >= 0:
+		return ""
+	}
+	return class[:1]
+}
+
+// knownDifferences are the classes native lexers give where pygments gives
+// others, as "native pygments" pairs, in places the table-driven lexers don't
+// go into.
+var knownDifferences = map[string][]string{
+	// Interpolated variables are part of the string
+	"bash":   {"s2 nv"},
+	"kotlin": {"s n"},
+	"ruby":   {"s2 nb", "s2 n"},
+	// Builtin types are keywords, pygments takes them for names there
+	"scala": {"kt nc"},
+	"swift": {"kt nb"},
+	// void is a type in annotations, pygments takes it for the operator
+	"typescript": {"kt ow"},
+	// Units go with their numbers
+	"css": {"mi kt"},
+	// Image tags look like numbers
+	"docker": {"mf s"},
+	// Pygments leaves every plain scalar a literal, numbers and booleans too
+	"yaml": {"mi l", "kc l"},
+}
+
+func known(language, native, pygments string) bool {
+	for _, d := range knownDifferences[language] {
+		if d == native+" "+pygments {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNativeMatchesPygments(t *testing.T) {
+	for _, l := range languages.All {
+		l := l
+		t.Run(string(l.Id), func(t *testing.T) {
+			samples, err := filepath.Glob(filepath.Join("testdata", string(l.Id)+".*"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sample string
+			for _, s := range samples {
+				if !strings.HasSuffix(s, ".pygments.html") {
+					sample = s
+				}
+			}
+			if sample == "" {
+				t.Fatalf("no sample of %s in testdata", l.Id)
+			}
+			code, err := ioutil.ReadFile(sample)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", string(l.Id)+".pygments.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := Native{}.Render(string(code), l.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			native, pygments := classes(t, out), classes(t, string(golden))
+			if len(native) != len(pygments) {
+				t.Fatalf("%d lines, pygments has %d", len(native), len(pygments))
+			}
+			for i := range native {
+				if len(native[i]) != len(pygments[i]) {
+					t.Errorf("line %d has %d characters, pygments has %d", i+1, len(native[i]), len(pygments[i]))
+					continue
+				}
+				for j := range native[i] {
+					n, p := native[i][j], pygments[i][j]
+					if tokenKind(n) != "" && tokenKind(n) != tokenKind(p) && !known(string(l.Id), n, p) {
+						t.Errorf("line %d, column %d: class %q, pygments has %q", i+1, j+1, n, p)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNativeKeepsEveryLine(t *testing.T) {
+	for _, code := range []string{"\n\nfoo := 1\nbar := 2\n", "x\n\n\n", "\n", "no newline"} {
+		out, err := Native{}.Render(code, "go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Count(strings.TrimSuffix(code, "\n"), "\n") + 1
+		if got := strings.Count(out, "\n"); got != want || !strings.HasSuffix(out, "\n") {
+			t.Errorf("%q renders to %q, %d lines, want %d", code, out, got, want)
+		}
+	}
+}
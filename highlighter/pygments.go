@@ -0,0 +1,33 @@
+package highlighter
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+)
+
+// Pygments highlights code with pygmentize, which has to be installed. Blank
+// lines at both ends of the code are kept, so that lines of the output match
+// lines of the input.
+type Pygments struct{}
+
+func (Pygments) Render(contents string, language types.ProgrammingLanguage) (string, error) {
+	input, err := ioutil.TempFile("", "")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(input.Name())
+	defer input.Close()
+
+	if _, err = input.WriteString(contents); err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("pygmentize", "-l", string(lexerOf(language)), "-f", "html", "-O", "nowrap,stripnl=False", input.Name())
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
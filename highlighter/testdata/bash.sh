@@ -0,0 +1,7 @@
+
+# greet prints a greeting
+greet() {
+  for i in 1 2 3; do
+    echo "hello $1 $i"
+  done
+}
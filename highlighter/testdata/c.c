@@ -0,0 +1,10 @@
+
+#include <stdio.h>
+
+/* greet prints a greeting */
+int main(void) {
+    for (int i = 0; i < 3; i++) {
+        printf("hello %d\n", i);
+    }
+    return 0;
+}
@@ -0,0 +1,10 @@
+
+#include <iostream>
+
+// greet prints a greeting
+int main() {
+    for (int i = 0; i < 3; i++) {
+        std::cout << "hello " << i << std::endl;
+    }
+    return 0;
+}
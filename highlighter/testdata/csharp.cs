@@ -0,0 +1,9 @@
+
+// Greeter prints greetings
+public class Greeter {
+    public static void Main(string[] args) {
+        for (int i = 0; i < 3; i++) {
+            System.Console.WriteLine("hello " + i);
+        }
+    }
+}
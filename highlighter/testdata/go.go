@@ -0,0 +1,12 @@
+
+package main
+
+import "fmt"
+
+// greet prints a greeting
+func greet(name string) error {
+	for i := 0; i < 3; i++ {
+		fmt.Println("hello", name, i)
+	}
+	return nil
+}
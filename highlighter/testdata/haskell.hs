@@ -0,0 +1,8 @@
+
+-- greet builds a greeting
+module Main where
+
+greet :: String -> String
+greet name = "hello " ++ name
+
+main = putStrLn (greet "world")
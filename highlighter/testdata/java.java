@@ -0,0 +1,9 @@
+
+// Greeter prints greetings
+public class Greeter {
+    public static void main(String[] args) {
+        for (int i = 0; i < 3; i++) {
+            System.out.println("hello " + i);
+        }
+    }
+}
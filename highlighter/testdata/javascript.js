@@ -0,0 +1,8 @@
+
+// greet logs a greeting
+function greet(name) {
+  for (let i = 0; i < 3; i++) {
+    console.log("hello", name, i);
+  }
+  return null;
+}
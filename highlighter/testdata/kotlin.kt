@@ -0,0 +1,8 @@
+
+// greet prints a greeting
+fun greet(name: String) {
+    for (i in 0 until 3) {
+        println("hello $name")
+    }
+    val x = 42
+}
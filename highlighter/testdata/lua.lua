@@ -0,0 +1,8 @@
+
+-- greet prints a greeting
+local function greet(name)
+  for i = 1, 3 do
+    print("hello", name, i)
+  end
+  return nil
+end
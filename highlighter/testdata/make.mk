@@ -0,0 +1,8 @@
+
+# build the server
+BINARY = splinter
+
+build:
+	go build -o $(BINARY) main.go
+
+.PHONY: build
@@ -0,0 +1,9 @@
+
+# greet prints a greeting
+sub greet {
+    my ($name) = @_;
+    for my $i (1..3) {
+        print "hello $name\n";
+    }
+    return 1;
+}
@@ -0,0 +1,8 @@
+<?php
+// greet prints a greeting
+function greet($name) {
+    for ($i = 0; $i < 3; $i++) {
+        echo "hello";
+    }
+    return null;
+}
@@ -0,0 +1,11 @@
+
+import sys
+
+# greet prints a greeting
+def greet(name):
+    for i in range(3):
+        print("hello", name, i)
+    return None
+
+class Greeter:
+    pass
@@ -0,0 +1,8 @@
+
+# greet prints a greeting
+def greet(name)
+  3.times do |i|
+    puts "hello #{name} #{i}"
+  end
+  nil
+end
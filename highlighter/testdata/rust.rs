@@ -0,0 +1,8 @@
+
+// greet prints a greeting
+fn main() {
+    for i in 0..3 {
+        println!("hello {}", i);
+    }
+    let x: i32 = 42;
+}
@@ -0,0 +1,8 @@
+
+// Greeter prints greetings
+object Greeter {
+  def greet(name: String): Unit = {
+    val x = 42
+    println("hello " + name)
+  }
+}
@@ -0,0 +1,6 @@
+
+-- users with their snippets
+SELECT u.name, count(*) FROM users u
+JOIN snippets s ON s.author = u.id
+WHERE u.name = 'admin' AND s.likes > 10
+GROUP BY u.name;
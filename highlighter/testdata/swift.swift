@@ -0,0 +1,8 @@
+
+// greet prints a greeting
+func greet(name: String) {
+    for i in 0..<3 {
+        print("hello", name, i)
+    }
+    let x = 42
+}
@@ -0,0 +1,7 @@
+
+// greet logs a greeting
+function greet(name: string): void {
+  for (let i = 0; i < 3; i++) {
+    console.log("hello", name, i);
+  }
+}
@@ -1,3 +1,5 @@
+create extension if not exists pg_trgm;
+
 create table "user"
 (
     id       serial primary key,
@@ -7,32 +9,215 @@ create table "user"
 
 create table snippet
 (
-    id          serial primary key,
-    contents    varchar   not null,
-    highlighted varchar   not null default '',
-    language    varchar   not null,
-    author      int       not null,
-    likes       int       not null default 0,
-    dislikes    int       not null default 0,
-    createdAt   timestamp not null,
-    constraint fk_author foreign key (author) references "user" (id)
+    id            serial primary key,
+    title         varchar     not null default '',
+    description   varchar     not null default '',
+    tags          varchar[]   not null default '{}',
+    visibility    varchar     not null default 'public',
+    filename      varchar     not null default '',
+    contents      varchar     not null,
+    highlighted   varchar     not null default '',
+    language      varchar     not null,
+    -- Contents of the files after the first one, joined for searching
+    otherContents varchar     not null default '',
+    author        int         not null,
+    likes         int         not null default 0,
+    dislikes      int         not null default 0,
+    revision      int         not null default 1,
+    forkedFrom    int,
+    expiresAt     timestamptz,
+    burnOnRead    boolean     not null default false,
+    identifiers   varchar[]   not null default '{}',
+    createdAt     timestamptz not null,
+    -- The simple configuration neither stems words nor drops stop words, both hurt searching code
+    search        tsvector generated always as (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', description), 'B') ||
+        setweight(to_tsvector('simple', contents || E'\n' || otherContents), 'C')
+    ) stored,
+    constraint fk_author foreign key (author) references "user" (id),
+    constraint valid_visibility check (visibility in ('public', 'unlisted', 'private'))
+);
+
+create index snippet_author_created on snippet (author, createdAt, id);
+create index snippet_author_score on snippet (author, (likes - dislikes), id);
+create index snippet_language_created on snippet (language, createdAt, id);
+create index snippet_language_score on snippet (language, (likes - dislikes), id);
+create index snippet_forked_from_created on snippet (forkedFrom, createdAt, id);
+create index snippet_tags on snippet using gin (tags);
+create index snippet_search on snippet using gin (search);
+create index snippet_identifiers on snippet using gin (identifiers);
+create index snippet_contents_trigrams on snippet using gin ((contents || E'\n' || otherContents) gin_trgm_ops);
+create index snippet_expires on snippet (expiresAt) where expiresAt is not null;
+
+-- Ids of reaped snippets, so that they keep answering 410 Gone instead of 404
+create table expired
+(
+    snippet   int primary key,
+    expiredAt timestamptz not null
+);
+
+create table revision
+(
+    snippet     int         not null,
+    number      int         not null,
+    filename    varchar     not null default '',
+    contents    varchar     not null,
+    highlighted varchar     not null default '',
+    language    varchar     not null,
+    createdAt   timestamptz not null,
+    primary key (snippet, number),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+-- Files of multi-file snippets after the first one, which is kept in the snippet itself
+create table file
+(
+    snippet     int     not null,
+    position    int     not null,
+    name        varchar not null,
+    contents    varchar not null,
+    highlighted varchar not null default '',
+    language    varchar not null,
+    primary key (snippet, position),
+    unique (snippet, name),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index file_language on file (language, snippet);
+
+-- Files of revisions after the first one, which is kept in the revision itself
+create table revision_file
+(
+    snippet     int     not null,
+    number      int     not null,
+    position    int     not null,
+    name        varchar not null,
+    contents    varchar not null,
+    highlighted varchar not null default '',
+    language    varchar not null,
+    primary key (snippet, number, position),
+    constraint fk_revision foreign key (snippet, number) references revision (snippet, number) on delete cascade
 );
 
 create table comment
 (
     id        serial primary key,
-    contents  varchar   not null,
-    snippet   int       not null,
-    author    int       not null,
-    createdAt timestamp not null,
+    parent    int,
+    depth     int         not null default 0,
+    contents  varchar     not null,
+    rendered  varchar     not null default '',
+    snippet   int         not null,
+    revision  int         not null default 1,
+    file      int         not null default 0,
+    lineFrom  int,
+    lineTo    int,
+    author    int         not null,
+    likes     int         not null default 0,
+    dislikes  int         not null default 0,
+    deleted   boolean     not null default false,
+    createdAt timestamptz not null,
+    editedAt  timestamptz,
+    search    tsvector generated always as (to_tsvector('simple', contents)) stored,
     constraint fk_author foreign key (author) references "user" (id),
-    constraint fk_snippet foreign key (snippet) references snippet (id)
+    constraint fk_parent foreign key (parent) references comment (id) on delete cascade,
+    constraint valid_lines check (lineFrom between 1 and lineTo),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index comment_snippet_created on comment (snippet, createdAt, id) where parent is null;
+create index comment_snippet_score on comment (snippet, (likes - dislikes), id) where parent is null;
+create index comment_parent on comment (parent);
+
+create table comment_revision
+(
+    comment   int         not null,
+    contents  varchar     not null,
+    createdAt timestamptz not null,
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
 );
 
+create index comment_revision_comment on comment_revision (comment, createdAt);
+create index comment_search on comment using gin (search);
+
 create table vote
 (
     snippet int not null,
     "user"  int not null,
     vote    int not null,
-    primary key (snippet, "user")
+    primary key (snippet, "user"),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create table comment_vote
+(
+    comment int not null,
+    "user"  int not null,
+    vote    int not null,
+    primary key (comment, "user"),
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
+);
+
+create table reaction
+(
+    snippet  int     not null,
+    "user"   int     not null,
+    reaction varchar not null,
+    primary key (snippet, "user", reaction),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create table comment_reaction
+(
+    comment  int     not null,
+    "user"   int     not null,
+    reaction varchar not null,
+    primary key (comment, "user", reaction),
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
+);
+
+create table follow
+(
+    follower int not null,
+    followee int not null,
+    primary key (follower, followee),
+    constraint fk_follower foreign key (follower) references "user" (id),
+    constraint fk_followee foreign key (followee) references "user" (id),
+    constraint no_self_follow check (follower <> followee)
+);
+
+create table bookmark
+(
+    "user"    int         not null,
+    snippet   int         not null,
+    createdAt timestamptz not null,
+    primary key ("user", snippet),
+    constraint fk_user foreign key ("user") references "user" (id),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index bookmark_user_created on bookmark ("user", createdAt);
+
+create table collection
+(
+    id        serial primary key,
+    owner     int         not null,
+    name      varchar     not null,
+    public    boolean     not null default false,
+    createdAt timestamptz not null,
+    constraint fk_owner foreign key (owner) references "user" (id)
+);
+
+create index collection_owner on collection (owner, createdAt);
+
+create table collection_snippet
+(
+    collection int not null,
+    snippet    int not null,
+    position   int not null,
+    primary key (collection, snippet),
+    constraint fk_collection foreign key (collection) references collection (id) on delete cascade,
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
 );
+
+create index collection_snippet_position on collection_snippet (collection, position);
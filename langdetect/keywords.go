@@ -0,0 +1,199 @@
+package langdetect
+
+import (
+	"github.com/mp-hl-2021/splinter/languages"
+	"github.com/mp-hl-2021/splinter/types"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// profile lists tokens typical for a language with their weights.
+type profile struct {
+	language types.ProgrammingLanguage
+	fold     bool // Keywords are case-insensitive
+	weights  map[string]float64
+}
+
+var profiles = []profile{
+	{language: "go", weights: map[string]float64{
+		"package": 1, "func": 2, ":=": 2, "chan": 3, "defer": 3, "fmt": 3, "nil": 1.5,
+		"struct": 1, "interface": 1, "<-": 1.5, "range": 1.5, "err": 1, "go": 0.5,
+	}},
+	{language: "python", weights: map[string]float64{
+		"def": 2, "self": 3, "elif": 4, "None": 3, "True": 1, "False": 1, "lambda": 1.5,
+		"__init__": 4, "__name__": 4, "print": 1, "pass": 2, "except": 3, "yield": 1, "import": 0.5, "from": 0.5,
+	}},
+	{language: "javascript", weights: map[string]float64{
+		"function": 2, "const": 1, "let": 1.5, "var": 1, "=>": 1, "===": 3, "!==": 3,
+		"console": 3, "undefined": 2, "require": 2, "document": 3, "window": 3, "async": 0.5, "await": 0.5,
+	}},
+	{language: "typescript", weights: map[string]float64{
+		"interface": 1.5, "readonly": 3, "keyof": 4, "implements": 1, "enum": 1, "number": 2, "boolean": 2,
+		"string": 1, "any": 1.5, "export": 1, "const": 0.5, "let": 1, "=>": 1, "===": 2, "console": 2,
+	}},
+	{language: "java", weights: map[string]float64{
+		"public": 1.5, "class": 1, "static": 1, "void": 1.5, "System": 3, "String": 1.5, "extends": 1.5,
+		"implements": 1.5, "final": 2, "throws": 3, "@Override": 4, "new": 0.5, "private": 1,
+	}},
+	{language: "c", weights: map[string]float64{
+		"#include": 3, "#define": 2, "printf": 2, "int": 0.5, "char": 1, "void": 0.5, "struct": 1,
+		"malloc": 3, "free": 1.5, "NULL": 2, "sizeof": 2, "unsigned": 2, "typedef": 2, "->": 1,
+	}},
+	{language: "cpp", weights: map[string]float64{
+		"#include": 2, "std": 4, "::": 2, "template": 3, "typename": 4, "cout": 4, "namespace": 3,
+		"class": 1, "nullptr": 4, "auto": 1, "virtual": 3, "<<": 1, "public": 0.5, "const": 0.5,
+	}},
+	{language: "csharp", weights: map[string]float64{
+		"using": 2, "namespace": 2, "public": 1, "Console": 4, "string": 1, "void": 0.5,
+		"get": 1, "set": 1, "override": 1.5, "var": 0.5, "class": 0.5,
+	}},
+	{language: "rust", weights: map[string]float64{
+		"fn": 3, "let": 1, "mut": 4, "impl": 3, "pub": 2, "match": 1.5, "println": 2, "->": 1, "::": 1.5,
+		"crate": 4, "Some": 2, "unwrap": 4, "Vec": 2, "trait": 3, "use": 1,
+	}},
+	{language: "ruby", weights: map[string]float64{
+		"def": 1.5, "end": 3, "puts": 3, "elsif": 4, "require": 1, "do": 1, "attr_accessor": 4,
+		"nil": 1.5, "unless": 2, "module": 1, "each": 1,
+	}},
+	{language: "php", weights: map[string]float64{
+		"<?php": 6, "$": 2, "echo": 2, "function": 1, "->": 1, "array": 1.5, "=>": 1, "foreach": 2,
+	}},
+	{language: "bash", weights: map[string]float64{
+		"echo": 2, "fi": 4, "then": 2, "esac": 4, "done": 2, "$": 1, "export": 1.5, "local": 1,
+		"do": 1, "grep": 2, "${": 2,
+	}},
+	{language: "haskell", weights: map[string]float64{
+		"::": 2, "->": 1, "where": 2, "data": 1.5, "instance": 2, "module": 1, "deriving": 4,
+		"putStrLn": 4, "Maybe": 3, "IO": 3, "of": 1, "<-": 1,
+	}},
+	{language: "kotlin", weights: map[string]float64{
+		"fun": 4, "val": 3, "var": 1, "println": 1, "override": 1, "object": 1, "when": 2, "companion": 4,
+	}},
+	{language: "swift", weights: map[string]float64{
+		"func": 2, "let": 1, "var": 1, "guard": 4, "print": 1, "protocol": 3, "extension": 2,
+		"self": 1, "nil": 1, "Int": 1.5, "String": 1,
+	}},
+	{language: "scala", weights: map[string]float64{
+		"def": 1.5, "val": 2, "object": 2, "case": 1, "extends": 1, "implicit": 4, "trait": 2, "sealed": 3,
+	}},
+	{language: "sql", fold: true, weights: map[string]float64{
+		"select": 3, "from": 1.5, "where": 1.5, "insert": 3, "into": 2, "update": 2, "create": 2,
+		"table": 2, "join": 2, "group": 1, "order": 1, "by": 1,
+	}},
+	{language: "lua", weights: map[string]float64{
+		"local": 2, "function": 1, "end": 2, "then": 1, "elseif": 3, "nil": 1, "~=": 4, "..": 1,
+		"pairs": 3, "ipairs": 4,
+	}},
+	{language: "perl", weights: map[string]float64{
+		"my": 3, "sub": 2, "use": 1, "strict": 3, "$": 1, "@": 1, "foreach": 1, "elsif": 3, "unless": 1, "qw": 4,
+	}},
+	{language: "html", fold: true, weights: map[string]float64{
+		"<html": 5, "<!doctype": 5, "<div": 3, "<head": 4, "<body": 4, "<span": 3, "<p": 2, "</": 2, "<a": 1,
+	}},
+	{language: "css", weights: map[string]float64{
+		"px": 2, "color": 2, "margin": 3, "padding": 3, "font": 1.5, "display": 2, "background": 2, "em": 1,
+	}},
+}
+
+// operators are tokens made of punctuation the profiles care about, longest first.
+var operators = []string{"<?php", "===", "!==", ":=", "=>", "->", "<-", "::", "<<", "~=", "..", "${", "</", "$", "@"}
+
+// tokens splits code into words, preprocessor directives, tags and the operators.
+func tokens(contents string) map[string]int {
+	counts := make(map[string]int)
+	runes := []rune(contents)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		next := i + 1
+		if r == '<' && next < len(runes) && runes[next] == '!' {
+			next++ // <!DOCTYPE and the like
+		}
+		switch {
+		case r == '_' || unicode.IsLetter(r) || (r == '#' || r == '@' || r == '<') && next < len(runes) && unicode.IsLetter(runes[next]):
+			start := i
+			for i = next; i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])); i++ {
+			}
+			counts[string(runes[start:i])]++
+			// Tags and annotations also count as the markers
+			if r == '<' || r == '@' {
+				counts[string(r)]++
+			}
+			continue
+		}
+
+		matched := false
+		for _, op := range operators {
+			if strings.HasPrefix(string(runes[i:min(i+len(op), len(runes))]), op) {
+				counts[op]++
+				i += len([]rune(op))
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	return counts
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// classify scores languages by their keywords in contents. If candidates
+// aren't empty, only they are considered.
+func classify(contents string, candidates []types.ProgrammingLanguage) types.LanguageGuess {
+	counts := tokens(contents)
+	folded := make(map[string]int, len(counts))
+	for t, n := range counts {
+		folded[strings.ToLower(t)] += n
+	}
+
+	allowed := make(map[types.ProgrammingLanguage]bool, len(candidates))
+	for _, c := range candidates {
+		allowed[c] = true
+	}
+
+	type score struct {
+		language types.ProgrammingLanguage
+		value    float64
+	}
+	var scores []score
+	total := 0.0
+	for _, p := range profiles {
+		if len(allowed) > 0 && !allowed[p.language] {
+			continue
+		}
+		source := counts
+		if p.fold {
+			source = folded
+		}
+		value := 0.0
+		for t, w := range p.weights {
+			if n := source[t]; n > 0 {
+				// Repetition adds evidence, but slower than new keywords do
+				value += w * (1 + math.Log(float64(n)))
+			}
+		}
+		if value > 0 {
+			scores = append(scores, score{p.language, value})
+			total += value
+		}
+	}
+	if len(scores) == 0 {
+		return types.LanguageGuess{Language: languages.Text}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].value > scores[j].value
+	})
+	best := scores[0]
+	confidence := best.value / total * math.Min(1, best.value/minEvidence)
+	return types.LanguageGuess{Language: best.language, Confidence: math.Round(confidence*100) / 100}
+}
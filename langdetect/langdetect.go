@@ -0,0 +1,115 @@
+// Package langdetect guesses programming languages of files from their names
+// and contents: modelines, shebangs, extensions and, failing those, keywords
+// typical for each language. Guesses are languages of the registry, plain text
+// if nothing is known about a file.
+package langdetect
+
+import (
+	"encoding/json"
+	"github.com/mp-hl-2021/splinter/languages"
+	"github.com/mp-hl-2021/splinter/types"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Confidence of the signals, keywords give their own
+const (
+	modelineConfidence  = 0.95
+	shebangConfidence   = 0.9
+	extensionConfidence = 0.85
+	jsonConfidence      = 0.8
+)
+
+// minEvidence is the keyword score at which keywords alone are trusted fully
+const minEvidence = 12.0
+
+var (
+	vimModelineRe   = regexp.MustCompile(`(?:^|\s)(?:vi|vim|ex):.*?\b(?:ft|filetype|syntax)=([\w+#-]+)`)
+	emacsModelineRe = regexp.MustCompile(`-\*-\s*(?:.*?\bmode:\s*)?([\w+#-]+)\s*(?:;.*?)?-\*-`)
+	versionRe       = regexp.MustCompile(`[\d.]+$`)
+)
+
+// Detect guesses the language of a file, filename may be empty.
+func Detect(filename, contents string) types.LanguageGuess {
+	if l, ok := modeline(contents); ok {
+		return types.LanguageGuess{Language: l, Confidence: modelineConfidence}
+	}
+	if l, ok := shebang(contents); ok {
+		return types.LanguageGuess{Language: l, Confidence: shebangConfidence}
+	}
+
+	var candidates []types.ProgrammingLanguage
+	for _, l := range languages.ByFilename(filename) {
+		candidates = append(candidates, l.Id)
+	}
+	if len(candidates) == 1 {
+		return types.LanguageGuess{Language: candidates[0], Confidence: extensionConfidence}
+	}
+	if len(candidates) == 0 && looksLikeJSON(contents) {
+		return types.LanguageGuess{Language: "json", Confidence: jsonConfidence}
+	}
+
+	guess := classify(contents, candidates)
+	if guess.Language == languages.Text && len(candidates) > 0 {
+		// The extension narrowed it down, keywords failed to pick one
+		return types.LanguageGuess{Language: candidates[0], Confidence: extensionConfidence / float64(len(candidates))}
+	}
+	return guess
+}
+
+// modeline looks for vim and emacs modelines naming known languages in the
+// first and the last lines.
+func modeline(contents string) (types.ProgrammingLanguage, bool) {
+	lines := strings.Split(contents, "\n")
+	if len(lines) > 10 {
+		lines = append(lines[:5:5], lines[len(lines)-5:]...)
+	}
+	for _, line := range lines {
+		m := vimModelineRe.FindStringSubmatch(line)
+		if m == nil {
+			m = emacsModelineRe.FindStringSubmatch(line)
+		}
+		if m == nil {
+			continue
+		}
+		if l, ok := languages.Lookup(m[1]); ok {
+			return l.Id, true
+		}
+	}
+	return "", false
+}
+
+// shebang recognizes interpreters in #! lines, including the ones run through env.
+func shebang(contents string) (types.ProgrammingLanguage, bool) {
+	if !strings.HasPrefix(contents, "#!") {
+		return "", false
+	}
+	line := strings.SplitN(contents[2:], "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interpreter = path.Base(f)
+				break
+			}
+		}
+	}
+	interpreter = versionRe.ReplaceAllString(interpreter, "")
+
+	if l, ok := languages.Lookup(interpreter); ok && l.Id != languages.Text {
+		return l.Id, true
+	}
+	return "", false
+}
+
+func looksLikeJSON(contents string) bool {
+	trimmed := strings.TrimSpace(contents)
+	return (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed))
+}
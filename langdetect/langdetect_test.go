@@ -0,0 +1,71 @@
+package langdetect
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		contents string
+		want     types.ProgrammingLanguage
+	}{
+		// Extensions and file names
+		{"go extension", "main.go", "x", "go"},
+		{"python extension", "tool.py", "x", "python"},
+		{"upper case extension", "Main.JAVA", "x", "java"},
+		{"extension in a directory", "src/lib.rs", "x", "rust"},
+		{"file name", "Dockerfile", "x", "docker"},
+		{"file name in a directory", "build/Makefile", "x", "make"},
+		{"text extension", "notes.txt", "def f(self): pass", "text"},
+		{"header of c", "list.h", "struct list { struct list *next; };\nvoid free_list(struct list *l);", "c"},
+		{"header of c++", "list.h", "namespace util {\ntemplate <typename T> class List {\npublic:\n  std::vector<T> items;\n};\n}", "cpp"},
+
+		// Shebangs go before extensions
+		{"shebang", "", "#!/bin/bash\necho hi", "bash"},
+		{"shebang through env", "", "#!/usr/bin/env python3\nprint(1)", "python"},
+		{"env with flags", "", "#!/usr/bin/env -S node --harmony\nconsole.log(1)", "javascript"},
+		{"versioned interpreter", "", "#!/usr/local/bin/ruby2.7\nputs 1", "ruby"},
+		{"shebang over extension", "script.txt", "#!/bin/sh\nls", "bash"},
+		{"unknown interpreter", "", "#!/usr/bin/frobnicate\n", "text"},
+
+		// Modelines go before everything
+		{"vim modeline", "x.txt", "# vim: set ft=python :\nx = 1", "python"},
+		{"emacs modeline", "", "// -*- mode: go -*-\nx", "go"},
+
+		// Contents alone
+		{"go", "", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tx := 1\n\tdefer fmt.Println(x)\n}\n", "go"},
+		{"python", "", "class A:\n    def __init__(self):\n        self.x = None\n\nif __name__ == '__main__':\n    A()\n", "python"},
+		{"javascript", "", "const x = require('x');\nfunction f() {\n  if (x === undefined) console.log('no');\n}\n", "javascript"},
+		{"java", "", "public class Main {\n    @Override\n    public String toString() {\n        return \"x\";\n    }\n    public static void main(String[] args) {\n        System.out.println(1);\n    }\n}\n", "java"},
+		{"json", "", `{"a": [1, 2, {"b": null}]}`, "json"},
+		{"prose", "", "Just a few words about nothing in particular.", "text"},
+		{"empty", "", "", "text"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			guess := Detect(test.filename, test.contents)
+			if guess.Language != test.want {
+				t.Errorf("Detect(%q, %q) = %q, want %q", test.filename, test.contents, guess.Language, test.want)
+			}
+			if guess.Confidence < 0 || guess.Confidence > 1 {
+				t.Errorf("confidence %v is out of [0, 1]", guess.Confidence)
+			}
+		})
+	}
+}
+
+func TestConfidenceOfSignals(t *testing.T) {
+	code := "package main\n\nfunc main() {\n\tx := 1\n\tdefer fmt.Println(x)\n}\n"
+	byContents := Detect("", code)
+	byExtension := Detect("main.go", code)
+	byShebang := Detect("", "#!/usr/bin/env python\n"+code)
+	if byShebang.Confidence != shebangConfidence || byExtension.Confidence != extensionConfidence {
+		t.Errorf("confidences %v by shebang and %v by extension, want %v and %v", byShebang.Confidence, byExtension.Confidence, shebangConfidence, extensionConfidence)
+	}
+	if byContents.Confidence <= 0 || byContents.Confidence > 1 {
+		t.Errorf("confidence %v by contents, want within (0, 1]", byContents.Confidence)
+	}
+}
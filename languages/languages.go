@@ -0,0 +1,84 @@
+// Package languages is the registry of languages snippets may be written in.
+package languages
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"path"
+	"strings"
+)
+
+// Text is the language of plain text, for files in no language in particular
+const Text types.ProgrammingLanguage = "text"
+
+// All lists the supported languages ordered by their identifiers.
+var All = []types.Language{
+	{Id: "bash", Name: "Bash", Aliases: []string{"sh", "shell", "shell-script", "zsh", "ksh", "dash"}, Extensions: []string{".sh", ".bash", ".zsh"}, Filenames: []string{".bashrc", ".bash_profile", ".profile", ".zshrc"}},
+	{Id: "c", Name: "C", Extensions: []string{".c", ".h"}},
+	{Id: "cpp", Name: "C++", Aliases: []string{"c++", "cxx"}, Extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp", ".h"}},
+	{Id: "csharp", Name: "C#", Aliases: []string{"c#", "cs"}, Extensions: []string{".cs"}},
+	{Id: "css", Name: "CSS", Extensions: []string{".css"}},
+	{Id: "docker", Name: "Dockerfile", Aliases: []string{"dockerfile"}, Filenames: []string{"Dockerfile"}},
+	{Id: "go", Name: "Go", Aliases: []string{"golang"}, Extensions: []string{".go"}},
+	{Id: "haskell", Name: "Haskell", Aliases: []string{"hs", "runhaskell", "runghc"}, Extensions: []string{".hs"}},
+	{Id: "html", Name: "HTML", Aliases: []string{"htm"}, Extensions: []string{".html", ".htm"}},
+	{Id: "java", Name: "Java", Extensions: []string{".java"}},
+	{Id: "javascript", Name: "JavaScript", Aliases: []string{"js", "node", "nodejs"}, Extensions: []string{".js", ".mjs", ".cjs", ".jsx"}},
+	{Id: "json", Name: "JSON", Extensions: []string{".json"}},
+	{Id: "kotlin", Name: "Kotlin", Aliases: []string{"kt"}, Extensions: []string{".kt", ".kts"}},
+	{Id: "lua", Name: "Lua", Extensions: []string{".lua"}},
+	{Id: "make", Name: "Makefile", Aliases: []string{"makefile", "mf"}, Extensions: []string{".mk"}, Filenames: []string{"Makefile", "GNUmakefile"}},
+	{Id: "markdown", Name: "Markdown", Aliases: []string{"md"}, Extensions: []string{".md", ".markdown"}},
+	{Id: "perl", Name: "Perl", Aliases: []string{"pl"}, Extensions: []string{".pl", ".pm"}},
+	{Id: "php", Name: "PHP", Extensions: []string{".php"}},
+	{Id: "python", Name: "Python", Aliases: []string{"py", "python2", "python3"}, Extensions: []string{".py", ".pyw"}},
+	{Id: "ruby", Name: "Ruby", Aliases: []string{"rb"}, Extensions: []string{".rb"}, Filenames: []string{"Gemfile", "Rakefile"}},
+	{Id: "rust", Name: "Rust", Aliases: []string{"rs"}, Extensions: []string{".rs"}},
+	{Id: "scala", Name: "Scala", Extensions: []string{".scala"}},
+	{Id: "sql", Name: "SQL", Extensions: []string{".sql"}},
+	{Id: "swift", Name: "Swift", Extensions: []string{".swift"}},
+	{Id: "text", Name: "Plain text", Aliases: []string{"txt", "plain", "plaintext"}, Extensions: []string{".txt"}},
+	{Id: "typescript", Name: "TypeScript", Aliases: []string{"ts"}, Extensions: []string{".ts", ".tsx"}},
+	{Id: "xml", Name: "XML", Extensions: []string{".xml"}},
+	{Id: "yaml", Name: "YAML", Aliases: []string{"yml"}, Extensions: []string{".yaml", ".yml"}},
+}
+
+var (
+	byName      = make(map[string]types.Language)
+	byExtension = make(map[string][]types.Language)
+	byFilename  = make(map[string]types.Language)
+)
+
+func init() {
+	for _, l := range All {
+		byName[string(l.Id)] = l
+		byName[strings.ToLower(l.Name)] = l
+		for _, a := range l.Aliases {
+			byName[a] = l
+		}
+		for _, e := range l.Extensions {
+			byExtension[e] = append(byExtension[e], l)
+		}
+		for _, f := range l.Filenames {
+			byFilename[strings.ToLower(f)] = l
+		}
+	}
+}
+
+// Lookup finds a language by its identifier, display name or an alias, ignoring case.
+func Lookup(name string) (types.Language, bool) {
+	l, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+	return l, ok
+}
+
+// ByFilename lists languages a file with the given name may be written in,
+// several for ambiguous extensions such as .h.
+func ByFilename(filename string) []types.Language {
+	if filename == "" {
+		return nil
+	}
+	base := strings.ToLower(path.Base(filename))
+	if l, ok := byFilename[base]; ok {
+		return []types.Language{l}
+	}
+	return byExtension[path.Ext(base)]
+}
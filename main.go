@@ -5,12 +5,15 @@ import (
 	"github.com/mp-hl-2021/splinter/api"
 	"github.com/mp-hl-2021/splinter/auth"
 	"github.com/mp-hl-2021/splinter/highlighter"
+	"github.com/mp-hl-2021/splinter/reaper"
 	"github.com/mp-hl-2021/splinter/storage"
+	"github.com/mp-hl-2021/splinter/types"
 	"github.com/mp-hl-2021/splinter/usecases"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,8 +23,37 @@ func main() {
 	connStr := flag.String("connStr", "user=postgres password=postgres host=db dbname=postgres sslmode=disable", "postgres connection string")
 	highlightWorkers := flag.String("highlightWorkers", "8", "number of highlighter workers")
 	highlightQueueSize := flag.String("highlightQueueSize", "256", "highlighter queue size")
+	highlightBackend := flag.String("highlightBackend", "native", "highlighter backend, native or pygments, which needs pygmentize installed")
+	reapInterval := flag.Duration("reapInterval", time.Minute, "how often to delete expired snippets")
+	moderators := flag.String("moderators", "", "comma separated ids of users allowed to moderate")
+	reactions := flag.String("reactions", "", "comma separated reactions users may leave, the default set if empty")
+	reconcileVotes := flag.Bool("reconcileVotes", false, "recompute snippet likes/dislikes from votes and exit")
+	normalizeLanguages := flag.Bool("normalizeLanguages", false, "replace legacy language names such as Go or golang with registry identifiers and exit")
 	flag.Parse()
 
+	postgres, err := storage.NewPostgres(*connStr)
+	if err != nil {
+		panic(err)
+	}
+
+	if *reconcileVotes {
+		n, err := postgres.ReconcileRatings()
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("Reconciled ratings of %d snippets", n)
+		return
+	}
+
+	if *normalizeLanguages {
+		n, err := postgres.NormalizeLanguages()
+		if err != nil {
+			panic(err)
+		}
+		log.Printf("Normalized languages of %d snippets", n)
+		return
+	}
+
 	privateKeyBytes, err := ioutil.ReadFile(*privateKeyPath)
 	publicKeyBytes, err := ioutil.ReadFile(*publicKeyPath)
 
@@ -30,17 +62,17 @@ func main() {
 		panic(err)
 	}
 
-	postgres, err := storage.NewPostgres(*connStr)
+	hq, err := strconv.Atoi(*highlightQueueSize)
 	if err != nil {
 		panic(err)
 	}
 
-	hq, err := strconv.Atoi(*highlightQueueSize)
-	if err != nil {
-		panic(err)
+	backend, ok := highlighter.Backends[*highlightBackend]
+	if !ok {
+		panic("unknown highlighter backend " + *highlightBackend)
 	}
 
-	h := highlighter.New(postgres, hq)
+	h := highlighter.New(postgres, hq, backend)
 
 	w, err := strconv.Atoi(*highlightWorkers)
 	if err != nil {
@@ -51,11 +83,24 @@ func main() {
 		go h.Run()
 	}
 
+	r := reaper.New(postgres, *reapInterval)
+	go r.Run()
+
+	m, err := parseUserIds(*moderators)
+	if err != nil {
+		panic(err)
+	}
+
 	userInterface := &usecases.DelegatedUserInterface{
-		UserStorage:    postgres,
-		SnippetStorage: postgres,
-		Auth:           a,
-		Highlighter:    h,
+		UserStorage:       postgres,
+		SnippetStorage:    postgres,
+		FollowStorage:     postgres,
+		SearchStorage:     postgres,
+		CollectionStorage: postgres,
+		Auth:              a,
+		Highlighter:       h,
+		Moderators:        m,
+		Reactions:         parseReactions(*reactions),
 	}
 
 	service := api.NewApi(userInterface, a)
@@ -74,3 +119,28 @@ func main() {
 		log.Panic(err)
 	}
 }
+
+func parseUserIds(s string) (map[types.UserId]bool, error) {
+	res := make(map[types.UserId]bool)
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		uid, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		res[types.UserId(uid)] = true
+	}
+	return res, nil
+}
+
+func parseReactions(s string) []types.Reaction {
+	var res []types.Reaction
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			res = append(res, types.Reaction(r))
+		}
+	}
+	return res
+}
@@ -0,0 +1,258 @@
+// Package markdown renders a safe subset of Markdown to HTML: paragraphs,
+// headings, block quotes, lists, fenced code blocks, code spans, emphasis
+// and links. Raw HTML is escaped and only http, https and mailto links
+// survive, so the output is safe to embed as is.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// CodeHighlighter renders a fenced code block in the given language.
+// On error the block is rendered as plain preformatted text.
+type CodeHighlighter func(code, language string) (string, error)
+
+// A single slash starts a path on this site in safeLinkRe, two of them or a
+// backslash after it, which browsers read as a slash, start a URL of another host.
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletRe   = regexp.MustCompile(`^\s{0,3}[-*+]\s+`)
+	orderedRe  = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+`)
+	fenceRe    = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*(\\w[\\w+#.-]*)?")
+	blockRe    = regexp.MustCompile("^\\s{0,3}(#{1,6}\\s|>|```|~~~)")
+	safeLinkRe = regexp.MustCompile(`^(?i)(https?://|mailto:|/$|/[^/\\]|#)`)
+)
+
+// maxQuoteDepth bounds nesting of block quotes, deeper quote markers are kept as text
+const maxQuoteDepth = 8
+
+func Render(source string, highlight CodeHighlighter) string {
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n"), highlight, 0)
+	return b.String()
+}
+
+// renderBlocks renders lines nested in depth block quotes.
+func renderBlocks(b *strings.Builder, lines []string, highlight CodeHighlighter, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			m := fenceRe.FindStringSubmatch(line)
+			fence, language := m[1], m[2]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // The closing fence
+			renderCode(b, strings.TrimRight(strings.Join(code, "\n"), "\n")+"\n", language, highlight)
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			tag := "h" + string(rune('0'+len(m[1])))
+			b.WriteString("<" + tag + ">")
+			renderInline(b, m[2])
+			b.WriteString("</" + tag + ">\n")
+			i++
+
+		case strings.HasPrefix(strings.TrimSpace(line), ">") && depth < maxQuoteDepth:
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				l := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(l, " "))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quote, highlight, depth+1)
+			b.WriteString("</blockquote>\n")
+
+		case bulletRe.MatchString(line) || orderedRe.MatchString(line):
+			marker, tag := bulletRe, "ul"
+			if !bulletRe.MatchString(line) {
+				marker, tag = orderedRe, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for i < len(lines) && marker.MatchString(lines[i]) {
+				item := []string{marker.ReplaceAllString(lines[i], "")}
+				// Indented lines continue the item
+				for i++; i < len(lines) && strings.HasPrefix(lines[i], "  ") && strings.TrimSpace(lines[i]) != ""; i++ {
+					item = append(item, strings.TrimSpace(lines[i]))
+				}
+				b.WriteString("<li>")
+				renderInline(b, strings.Join(item, "\n"))
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && (len(paragraph) == 0 || !startsBlock(lines[i])); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			b.WriteString("<p>")
+			renderInline(b, strings.Join(paragraph, "\n"))
+			b.WriteString("</p>\n")
+		}
+	}
+}
+
+func startsBlock(line string) bool {
+	return blockRe.MatchString(line) || bulletRe.MatchString(line) || orderedRe.MatchString(line)
+}
+
+func renderCode(b *strings.Builder, code, language string, highlight CodeHighlighter) {
+	if language != "" && highlight != nil {
+		if out, err := highlight(code, language); err == nil {
+			b.WriteString(out)
+			return
+		}
+	}
+	b.WriteString("<pre><code>")
+	b.WriteString(html.EscapeString(code))
+	b.WriteString("</code></pre>\n")
+}
+
+// renderInline renders code spans, emphasis, links and line breaks of text,
+// escaping everything else.
+func renderInline(b *strings.Builder, text string) {
+	renderSpans(b, text, true)
+}
+
+// renderSpans renders text inline, with links only if links is set, so that
+// labels of links don't nest links of their own.
+func renderSpans(b *strings.Builder, text string, links bool) {
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && strings.IndexByte("\\`*_[]()#+-.!>~", text[i+1]) >= 0:
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			run := len(text[i:]) - len(strings.TrimLeft(text[i:], "`"))
+			fence := text[i : i+run]
+			if end := strings.Index(text[i+run:], fence); end >= 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(strings.TrimSpace(text[i+run : i+run+end])))
+				b.WriteString("</code>")
+				i += run + end + run
+				continue
+			}
+			b.WriteString(fence)
+			i += run
+			continue
+
+		case c == '*' || c == '_':
+			if n, ok := renderEmphasis(b, text, i, links); ok {
+				i += n
+				continue
+			}
+
+		case c == '[' && links:
+			if n, ok := renderLink(b, text[i:]); ok {
+				i += n
+				continue
+			}
+
+		case c == 'h' && links && (strings.HasPrefix(text[i:], "http://") || strings.HasPrefix(text[i:], "https://")) && (i == 0 || !isWordByte(text[i-1])):
+			end := strings.IndexAny(text[i:], " \t\n<>\"")
+			if end < 0 {
+				end = len(text) - i
+			}
+			url := strings.TrimRight(text[i:i+end], ".,;:!?)'")
+			writeLink(b, url, html.EscapeString(url))
+			i += len(url)
+			continue
+
+		case c == '\n':
+			b.WriteString("<br>\n")
+			i++
+			continue
+		}
+
+		b.WriteString(html.EscapeString(text[i : i+1]))
+		i++
+	}
+}
+
+// renderEmphasis renders **strong** and *emphasized* text starting at text[i].
+// Underscores only count at word boundaries, so snake_case stays as it is.
+func renderEmphasis(b *strings.Builder, text string, i int, links bool) (int, bool) {
+	c := text[i]
+	if c == '_' && i > 0 && isWordByte(text[i-1]) {
+		return 0, false
+	}
+
+	delim, tag := text[i:i+1], "em"
+	if strings.HasPrefix(text[i:], strings.Repeat(delim, 2)) {
+		delim, tag = strings.Repeat(delim, 2), "strong"
+	}
+
+	start := i + len(delim)
+	if start >= len(text) || text[start] == ' ' || text[start] == '\n' {
+		return 0, false
+	}
+	end := strings.Index(text[start:], delim)
+	if end <= 0 || text[start+end-1] == ' ' {
+		return 0, false
+	}
+	after := start + end + len(delim)
+	if c == '_' && after < len(text) && isWordByte(text[after]) {
+		return 0, false
+	}
+
+	b.WriteString("<" + tag + ">")
+	renderSpans(b, text[start:start+end], links)
+	b.WriteString("</" + tag + ">")
+	return after - i, true
+}
+
+// renderLink renders [text](url) at the beginning of text. Links with
+// unsafe schemes such as javascript: are rendered as their text.
+func renderLink(b *strings.Builder, text string) (int, bool) {
+	closing := strings.Index(text, "](")
+	if closing < 0 || strings.Contains(text[:closing], "\n") {
+		return 0, false
+	}
+	// URLs may contain balanced parentheses
+	end, depth := -1, 0
+	for j := closing + 2; j < len(text) && end < 0; j++ {
+		switch text[j] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				end = j
+			}
+			depth--
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+
+	label, url := text[1:closing], strings.TrimSpace(text[closing+2:end])
+	var inner strings.Builder
+	renderSpans(&inner, label, false)
+	if safeLinkRe.MatchString(url) && !strings.ContainsAny(url, " \n") {
+		writeLink(b, url, inner.String())
+	} else {
+		b.WriteString(inner.String())
+	}
+	return end + 1, true
+}
+
+func writeLink(b *strings.Builder, url, label string) {
+	b.WriteString(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener">`)
+	b.WriteString(label)
+	b.WriteString("</a>")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,160 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "script tag",
+			source:   `<script>alert(1)</script>`,
+			contains: []string{"&lt;script&gt;alert(1)&lt;/script&gt;"},
+			excludes: []string{"<script"},
+		},
+		{
+			name:     "image with onerror",
+			source:   `<img src=x onerror="alert(1)">`,
+			contains: []string{"&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+			excludes: []string{"<img"},
+		},
+		{
+			name:     "raw html in a heading",
+			source:   `# <b onclick="x()">title</b>`,
+			contains: []string{"<h1>&lt;b onclick="},
+			excludes: []string{"<b "},
+		},
+		{
+			name:     "javascript link",
+			source:   `[click](javascript:alert(1))`,
+			contains: []string{"<p>click</p>"},
+			excludes: []string{"<a", "javascript:"},
+		},
+		{
+			name:     "mixed case javascript link",
+			source:   `[click](JaVaScRiPt:alert(1))`,
+			contains: []string{"<p>click</p>"},
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "data link",
+			source:   `[click](data:text/html;base64,PHNjcmlwdD4=)`,
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "vbscript link",
+			source:   `[click](vbscript:msgbox(1))`,
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "entity-encoded scheme",
+			source:   `[click](&#106;avascript:alert(1))`,
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "entity-encoded colon",
+			source:   `[click](javascript&#58;alert(1))`,
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "protocol-relative link",
+			source:   `[click](//evil.example/path)`,
+			contains: []string{"<p>click</p>"},
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "backslash after the slash",
+			source:   `[click](/\evil.example)`,
+			excludes: []string{"<a"},
+		},
+		{
+			name:     "path on the site",
+			source:   `[snippet](/snippets/1)`,
+			contains: []string{`<a href="/snippets/1" rel="nofollow noopener">snippet</a>`},
+		},
+		{
+			name:     "root of the site",
+			source:   `[home](/)`,
+			contains: []string{`<a href="/" rel="nofollow noopener">home</a>`},
+		},
+		{
+			name:     "https link",
+			source:   `[docs](https://golang.org/doc)`,
+			contains: []string{`<a href="https://golang.org/doc" rel="nofollow noopener">docs</a>`},
+		},
+		{
+			name:     "quotes in a link are escaped",
+			source:   `[x](https://a.example/"onmouseover="alert(1))`,
+			contains: []string{`href="https://a.example/&#34;onmouseover=&#34;alert(1)"`},
+		},
+		{
+			name:     "link in a link label",
+			source:   `[see [inner](https://inner.example)](https://outer.example)`,
+			excludes: []string{`<a href="https://inner.example" rel="nofollow noopener">inner</a>](`},
+		},
+		{
+			name:     "bare URL in a link label",
+			source:   `[https://inner.example](https://outer.example)`,
+			contains: []string{`<a href="https://outer.example" rel="nofollow noopener">https://inner.example</a>`},
+		},
+		{
+			name:     "unclosed code fence",
+			source:   "text\n\n```go\n<script>\nfunc main() {}",
+			contains: []string{"<pre><code>&lt;script&gt;\nfunc main() {}\n</code></pre>"},
+			excludes: []string{"<script"},
+		},
+		{
+			name:     "code span",
+			source:   "call `<b>` here",
+			contains: []string{"<code>&lt;b&gt;</code>"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := Render(test.source, nil)
+			for _, s := range test.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("%q renders to %q, want it to contain %q", test.source, out, s)
+				}
+			}
+			for _, s := range test.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("%q renders to %q, want no %q", test.source, out, s)
+				}
+			}
+		})
+	}
+}
+
+func TestNestedLinksDontNestAnchors(t *testing.T) {
+	out := Render(`[a [b](https://b.example) c](https://c.example)`, nil)
+	depth := 0
+	for i := 0; i < len(out); i++ {
+		switch {
+		case strings.HasPrefix(out[i:], "<a "):
+			depth++
+		case strings.HasPrefix(out[i:], "</a>"):
+			depth--
+		}
+		if depth > 1 {
+			t.Fatalf("%q nests anchors", out)
+		}
+	}
+}
+
+func TestQuoteNestingIsBounded(t *testing.T) {
+	out := Render(strings.Repeat(">", 10000)+" deep", nil)
+	if n := strings.Count(out, "<blockquote>"); n != maxQuoteDepth {
+		t.Errorf("%d nested block quotes, want %d", n, maxQuoteDepth)
+	}
+	if !strings.Contains(out, "deep") {
+		t.Errorf("%q lost the quoted text", out)
+	}
+}
@@ -0,0 +1,221 @@
+-- Upgrades a database created with the first initdb.sql, which had only the
+-- user, snippet, comment and vote tables, to the schema initdb.sql creates now:
+--
+--     psql <connection> -v ON_ERROR_STOP=1 -f migrate.sql
+--
+-- Timestamps without time zone are taken for UTC, the time zone of the server
+-- and the database in the docker-compose setup. After the upgrade, run splinter
+-- with -normalizeLanguages to replace legacy language names. Snippets posted
+-- before the upgrade aren't found by identifier code search until edited.
+
+begin;
+
+create extension if not exists pg_trgm;
+
+alter table snippet
+    alter column createdAt type timestamptz using createdAt at time zone 'UTC',
+    add column title         varchar     not null default '',
+    add column description   varchar     not null default '',
+    add column tags          varchar[]   not null default '{}',
+    add column visibility    varchar     not null default 'public',
+    add column filename      varchar     not null default '',
+    add column otherContents varchar     not null default '',
+    add column revision      int         not null default 1,
+    add column forkedFrom    int,
+    add column expiresAt     timestamptz,
+    add column burnOnRead    boolean     not null default false,
+    add column identifiers   varchar[]   not null default '{}',
+    add constraint valid_visibility check (visibility in ('public', 'unlisted', 'private'));
+
+alter table snippet
+    add column search tsvector generated always as (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', description), 'B') ||
+        setweight(to_tsvector('simple', contents || E'\n' || otherContents), 'C')
+    ) stored;
+
+create index snippet_author_created on snippet (author, createdAt, id);
+create index snippet_author_score on snippet (author, (likes - dislikes), id);
+create index snippet_language_created on snippet (language, createdAt, id);
+create index snippet_language_score on snippet (language, (likes - dislikes), id);
+create index snippet_forked_from_created on snippet (forkedFrom, createdAt, id);
+create index snippet_tags on snippet using gin (tags);
+create index snippet_search on snippet using gin (search);
+create index snippet_identifiers on snippet using gin (identifiers);
+create index snippet_contents_trigrams on snippet using gin ((contents || E'\n' || otherContents) gin_trgm_ops);
+create index snippet_expires on snippet (expiresAt) where expiresAt is not null;
+
+create table expired
+(
+    snippet   int primary key,
+    expiredAt timestamptz not null
+);
+
+create table revision
+(
+    snippet     int         not null,
+    number      int         not null,
+    filename    varchar     not null default '',
+    contents    varchar     not null,
+    highlighted varchar     not null default '',
+    language    varchar     not null,
+    createdAt   timestamptz not null,
+    primary key (snippet, number),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+-- Every snippet so far is its first revision
+insert into revision (snippet, number, contents, highlighted, language, createdAt)
+select id, 1, contents, highlighted, language, createdAt
+from snippet;
+
+create table file
+(
+    snippet     int     not null,
+    position    int     not null,
+    name        varchar not null,
+    contents    varchar not null,
+    highlighted varchar not null default '',
+    language    varchar not null,
+    primary key (snippet, position),
+    unique (snippet, name),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index file_language on file (language, snippet);
+
+create table revision_file
+(
+    snippet     int     not null,
+    number      int     not null,
+    position    int     not null,
+    name        varchar not null,
+    contents    varchar not null,
+    highlighted varchar not null default '',
+    language    varchar not null,
+    primary key (snippet, number, position),
+    constraint fk_revision foreign key (snippet, number) references revision (snippet, number) on delete cascade
+);
+
+alter table comment
+    alter column createdAt type timestamptz using createdAt at time zone 'UTC',
+    add column parent   int,
+    add column depth    int         not null default 0,
+    add column rendered varchar     not null default '',
+    add column revision int         not null default 1,
+    add column file     int         not null default 0,
+    add column lineFrom int,
+    add column lineTo   int,
+    add column likes    int         not null default 0,
+    add column dislikes int         not null default 0,
+    add column deleted  boolean     not null default false,
+    add column editedAt timestamptz,
+    add constraint fk_parent foreign key (parent) references comment (id) on delete cascade,
+    add constraint valid_lines check (lineFrom between 1 and lineTo),
+    drop constraint fk_snippet;
+
+alter table comment
+    add constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade,
+    add column search tsvector generated always as (to_tsvector('simple', contents)) stored;
+
+-- Comments used to be plain text, show them as such rather than as Markdown
+update comment
+set rendered = '<p>' || replace(replace(replace(replace(replace(contents,
+    '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;') || '</p>';
+
+create index comment_snippet_created on comment (snippet, createdAt, id) where parent is null;
+create index comment_snippet_score on comment (snippet, (likes - dislikes), id) where parent is null;
+create index comment_parent on comment (parent);
+
+create table comment_revision
+(
+    comment   int         not null,
+    contents  varchar     not null,
+    createdAt timestamptz not null,
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
+);
+
+create index comment_revision_comment on comment_revision (comment, createdAt);
+create index comment_search on comment using gin (search);
+
+-- Votes weren't tied to snippets, drop those left by nothing
+delete from vote
+where snippet not in (select id from snippet);
+
+alter table vote
+    add constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade;
+
+create table comment_vote
+(
+    comment int not null,
+    "user"  int not null,
+    vote    int not null,
+    primary key (comment, "user"),
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
+);
+
+create table reaction
+(
+    snippet  int     not null,
+    "user"   int     not null,
+    reaction varchar not null,
+    primary key (snippet, "user", reaction),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create table comment_reaction
+(
+    comment  int     not null,
+    "user"   int     not null,
+    reaction varchar not null,
+    primary key (comment, "user", reaction),
+    constraint fk_comment foreign key (comment) references comment (id) on delete cascade
+);
+
+create table follow
+(
+    follower int not null,
+    followee int not null,
+    primary key (follower, followee),
+    constraint fk_follower foreign key (follower) references "user" (id),
+    constraint fk_followee foreign key (followee) references "user" (id),
+    constraint no_self_follow check (follower <> followee)
+);
+
+create table bookmark
+(
+    "user"    int         not null,
+    snippet   int         not null,
+    createdAt timestamptz not null,
+    primary key ("user", snippet),
+    constraint fk_user foreign key ("user") references "user" (id),
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index bookmark_user_created on bookmark ("user", createdAt);
+
+create table collection
+(
+    id        serial primary key,
+    owner     int         not null,
+    name      varchar     not null,
+    public    boolean     not null default false,
+    createdAt timestamptz not null,
+    constraint fk_owner foreign key (owner) references "user" (id)
+);
+
+create index collection_owner on collection (owner, createdAt);
+
+create table collection_snippet
+(
+    collection int not null,
+    snippet    int not null,
+    position   int not null,
+    primary key (collection, snippet),
+    constraint fk_collection foreign key (collection) references collection (id) on delete cascade,
+    constraint fk_snippet foreign key (snippet) references snippet (id) on delete cascade
+);
+
+create index collection_snippet_position on collection_snippet (collection, position);
+
+commit;
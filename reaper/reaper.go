@@ -0,0 +1,35 @@
+package reaper
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"log"
+	"time"
+)
+
+// Reaper periodically deletes expired snippets along with their comments and votes.
+type Reaper struct {
+	storage  types.SnippetStorage
+	interval time.Duration
+}
+
+func New(storage types.SnippetStorage, interval time.Duration) Reaper {
+	return Reaper{
+		storage,
+		interval,
+	}
+}
+
+func (r *Reaper) Run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := r.storage.DeleteExpiredSnippets()
+		if err != nil {
+			log.Printf("Reaper error: %e", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("Reaped %d expired snippets", n)
+		}
+	}
+}
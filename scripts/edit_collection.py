@@ -0,0 +1,32 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    id = input("collection: ")
+    name = input("name (empty to keep): ")
+    public = input("public (y/n, empty to keep): ")
+    body = {}
+    if name:
+        body["Name"] = name
+    if public:
+        body["Public"] = public == "y"
+    r = requests.patch(f"http://localhost:5000/api/v1/collections/{id}", headers=build_headers(), json=body)
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,28 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    comment = input("comment: ")
+    contents = input("text: ")
+    r = requests.patch(f"http://localhost:5000/api/v1/comments/{comment}", headers=build_headers(), json={
+        "Contents": contents
+    })
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,34 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    if len(sys.argv) != 2:
+        print(f"Usage: {sys.argv[0]} <snippet>")
+        sys.exit(1)
+    id = input("id: ")
+    language = input("language (empty to keep): ")
+    with open(sys.argv[1]) as f:
+        contents = f.read()
+    body = { "Contents": contents }
+    if language:
+        body["Language"] = language
+    r = requests.patch(f"http://localhost:5000/api/v1/snippets/{id}", headers=build_headers(), json=body)
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
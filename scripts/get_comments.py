@@ -18,7 +18,8 @@ def build_headers():
 
 def main():
     snippet = input("snippet: ")
-    r = requests.get(f"http://localhost:5000/api/v1/snippets/{snippet}/comments", headers=build_headers())
+    sort = input("sort (top/new/old): ")
+    r = requests.get(f"http://localhost:5000/api/v1/snippets/{snippet}/comments", headers=build_headers(), params={"sort": sort})
     print(r.text)
 
 if __name__ == "__main__":
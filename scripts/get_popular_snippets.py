@@ -0,0 +1,31 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    rank = input("rank (score/wilson/hot): ")
+    window = input("window (all/day/week/month/year): ")
+    language = input("language: ")
+    r = requests.get(f"http://localhost:5000/api/v1/snippets/popular", headers=build_headers(), params={
+        "rank": rank,
+        "window": window,
+        "language": language
+    })
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
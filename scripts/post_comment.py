@@ -18,8 +18,15 @@ def build_headers():
 
 def main():
     snippet = input("snippet: ")
+    parent = input("reply to comment (empty for none): ")
+    file = input("file number, counting from 0 (empty for the first one): ")
+    lines = input("lines, e.g. 3 or 3-5 (empty for the whole snippet): ")
     contents = input("text: ")
+    first, _, last = lines.partition("-")
     r = requests.post(f"http://localhost:5000/api/v1/snippets/{snippet}/comments", headers=build_headers(), json={
+        "ParentId": int(parent or 0),
+        "File": int(file or 0),
+        "Lines": { "From": int(first or 0), "To": int(last or first or 0) },
         "Contents": contents
     })
     print(r.text)
@@ -1,5 +1,6 @@
 #!/usr/bin/env python3
 
+import os
 import requests
 import sys
 
@@ -17,15 +18,24 @@ def build_headers():
     return {}
 
 def main():
-    if len(sys.argv) != 2:
-        print(f"Usage: {sys.argv[0]} <snippet>")
+    if len(sys.argv) < 2:
+        print(f"Usage: {sys.argv[0]} <file>...")
         sys.exit(1)
-    language = input("language: ")
-    with open(sys.argv[1]) as f:
-        contents = f.read()
+    title = input("title: ")
+    tags = input("tags (comma separated): ")
+    files = []
+    for path in sys.argv[1:]:
+        language = input(f"language of {path} (empty to detect): ")
+        with open(path) as f:
+            files.append({"Name": os.path.basename(path), "Contents": f.read(), "Language": language.strip()})
+    ttl = input("ttl in seconds (empty to keep forever): ")
+    burn = input("burn after read? [y/N]: ")
     r = requests.post(f"http://localhost:5000/api/v1/snippets", headers=build_headers(), json={
-        "Language": language,
-        "Contents": contents
+        "Title": title,
+        "Tags": [tag for tag in tags.split(",") if tag.strip()],
+        "TTL": int(ttl or 0),
+        "BurnAfterRead": burn.strip().lower() == "y",
+        "Files": files
     })
     print(r.text)
 
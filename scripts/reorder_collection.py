@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    id = input("collection: ")
+    snippets = [int(s) for s in input("snippets in the new order (comma separated): ").split(",") if s.strip()]
+    r = requests.put(f"http://localhost:5000/api/v1/collections/{id}/order", headers=build_headers(), json={"Snippets": snippets})
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,31 @@
+#!/usr/bin/env python3
+
+import requests
+import sys
+
+def get_token():
+    try:
+        with open(".token") as f:
+            return f.read().strip()
+    except:
+        return None
+
+def build_headers():
+    token = get_token()
+    if token:
+        return { "Authorization": token }
+    return {}
+
+def main():
+    q = input("pattern: ")
+    mode = input("mode (identifier or regexp): ")
+    language = input("language (empty for any): ")
+    r = requests.get(f"http://localhost:5000/api/v1/search/code", headers=build_headers(), params={
+        "q": q,
+        "mode": mode,
+        "language": language
+    })
+    print(r.text)
+
+if __name__ == "__main__":
+    main()
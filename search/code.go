@@ -0,0 +1,140 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Identifiers returns identifiers of code with their normalized forms as terms.
+func Identifiers(code string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range code + " " {
+		isIdent := r == '_' || unicode.IsLetter(r) || start >= 0 && unicode.IsDigit(r)
+		if isIdent && start < 0 {
+			start = i
+		}
+		if !isIdent && start >= 0 {
+			if term := NormalizeIdentifier(code[start:i]); term != "" {
+				tokens = append(tokens, Token{Term: term, Start: start, End: i})
+			}
+			start = -1
+		}
+	}
+	return tokens
+}
+
+// NormalizeIdentifier lowercases ident and drops underscores,
+// so that parseRequest, ParseRequest and parse_request are the same.
+func NormalizeIdentifier(ident string) string {
+	return strings.ToLower(strings.ReplaceAll(ident, "_", ""))
+}
+
+// IdentifierParts splits camelCase, PascalCase and snake_case identifiers
+// into lowercased words: parseHTTPRequest becomes parse, http and request.
+func IdentifierParts(ident string) []string {
+	var parts []string
+	runes := []rune(ident)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			parts = append(parts, strings.ToLower(string(runes[start:end])))
+		}
+		start = end
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]):
+			// parse|Request
+			flush(i)
+		case i > start && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			// HTTP|Request
+			flush(i)
+		}
+	}
+	flush(len(runes))
+	return parts
+}
+
+// IdentifierTerms returns distinct terms to index code by: normalized
+// identifiers along with the words they consist of.
+func IdentifierTerms(code string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			res = append(res, term)
+		}
+	}
+	for _, t := range Identifiers(code) {
+		add(t.Term)
+		for _, p := range IdentifierParts(code[t.Start:t.End]) {
+			add(p)
+		}
+	}
+	return res
+}
+
+// IdentifierMatches returns byte ranges of identifiers of code which are
+// ident or consist of words including ident.
+func IdentifierMatches(code, ident string) [][]int {
+	term := NormalizeIdentifier(ident)
+	var res [][]int
+	for _, t := range Identifiers(code) {
+		if t.Term == term {
+			res = append(res, []int{t.Start, t.End})
+			continue
+		}
+		for _, p := range IdentifierParts(code[t.Start:t.End]) {
+			if p == term {
+				res = append(res, []int{t.Start, t.End})
+				break
+			}
+		}
+	}
+	return res
+}
+
+// RegexpMatches returns byte ranges of non-empty matches of re in code.
+func RegexpMatches(code string, re *regexp.Regexp) [][]int {
+	var res [][]int
+	for _, m := range re.FindAllStringIndex(code, -1) {
+		if m[1] > m[0] {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// LineHeadline picks up to MaxFragments lines of code containing matches,
+// marked up the same way as in Headline.
+func LineHeadline(code string, matches [][]int) string {
+	var fragments []string
+	for i := 0; i < len(matches) && len(fragments) < MaxFragments; {
+		start := strings.LastIndexByte(code[:matches[i][0]], '\n') + 1
+		end := len(code)
+		if n := strings.IndexByte(code[matches[i][0]:], '\n'); n >= 0 {
+			end = matches[i][0] + n
+		}
+
+		var b strings.Builder
+		last := start
+		for ; i < len(matches) && matches[i][0] < end; i++ {
+			matchEnd := matches[i][1]
+			if matchEnd > end {
+				matchEnd = end
+			}
+			b.WriteString(code[last:matches[i][0]])
+			b.WriteString(MatchStart + code[matches[i][0]:matchEnd] + MatchEnd)
+			last = matchEnd
+		}
+		b.WriteString(code[last:end])
+		fragments = append(fragments, b.String())
+	}
+	return strings.Join(fragments, FragmentDelimiter)
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// Markers of headlines, the same ones the Postgres storage asks ts_headline for
+const (
+	MatchStart        = "\x02"
+	MatchEnd          = "\x03"
+	FragmentDelimiter = "\x1f"
+)
+
+const (
+	MaxFragments  = 3
+	FragmentWords = 12 // Number of words around a match in a fragment
+)
+
+// Headline picks up to MaxFragments fragments of text around the terms of q,
+// wraps matched words into MatchStart and MatchEnd and joins fragments with
+// FragmentDelimiter, like ts_headline does.
+func Headline(text string, q Query) string {
+	tokens := Tokenize(text)
+	wanted := q.Terms()
+
+	var fragments []string
+	for i := 0; i < len(tokens) && len(fragments) < MaxFragments; i++ {
+		if !wanted[tokens[i].Term] {
+			continue
+		}
+
+		start := i - FragmentWords/2
+		if start < 0 {
+			start = 0
+		}
+		end := i + FragmentWords/2
+		for j := i; j <= end && j < len(tokens); j++ {
+			if wanted[tokens[j].Term] {
+				end = j + FragmentWords/2
+			}
+		}
+		if end >= len(tokens) {
+			end = len(tokens) - 1
+		}
+
+		var b strings.Builder
+		last := tokens[start].Start
+		for j := start; j <= end; j++ {
+			b.WriteString(text[last:tokens[j].Start])
+			if wanted[tokens[j].Term] {
+				b.WriteString(MatchStart + text[tokens[j].Start:tokens[j].End] + MatchEnd)
+			} else {
+				b.WriteString(text[tokens[j].Start:tokens[j].End])
+			}
+			last = tokens[j].End
+		}
+		fragments = append(fragments, b.String())
+		i = end
+	}
+	return strings.Join(fragments, FragmentDelimiter)
+}
+
+// RenderFragments turns headlines into HTML-escaped fragments with matches
+// wrapped into <mark>. Fragments without matches are dropped.
+func RenderFragments(headlines ...string) []string {
+	var res []string
+	for _, h := range headlines {
+		for _, f := range strings.Split(h, FragmentDelimiter) {
+			if !strings.Contains(f, MatchStart) || len(res) == MaxFragments {
+				continue
+			}
+			f = html.EscapeString(strings.TrimSpace(f))
+			f = strings.ReplaceAll(f, MatchStart, "<mark>")
+			f = strings.ReplaceAll(f, MatchEnd, "</mark>")
+			res = append(res, f)
+		}
+	}
+	return res
+}
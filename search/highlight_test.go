@@ -0,0 +1,54 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeadline(t *testing.T) {
+	mark := func(s string) string {
+		return MatchStart + s + MatchEnd
+	}
+	tests := []struct {
+		text, query, want string
+	}{
+		{"nothing here", "missing", ""},
+		{"Binary search, binary trees", "binary", mark("Binary") + " search, " + mark("binary") + " trees"},
+		{"parse_request(r)", "request", "parse_" + mark("request") + "(r"},
+	}
+	for _, test := range tests {
+		if got := Headline(test.text, ParseQuery(test.query)); got != test.want {
+			t.Errorf("Headline(%q, %q) = %q, want %q", test.text, test.query, got, test.want)
+		}
+	}
+}
+
+func TestHeadlineFragments(t *testing.T) {
+	filler := strings.Repeat(" filler", 2*FragmentWords)
+	text := strings.Repeat("match"+filler+" ", MaxFragments+1)
+	fragments := strings.Split(Headline(text, ParseQuery("match")), FragmentDelimiter)
+	if len(fragments) != MaxFragments {
+		t.Fatalf("%d fragments, want %d", len(fragments), MaxFragments)
+	}
+	for _, f := range fragments {
+		if strings.Count(f, MatchStart) != 1 {
+			t.Errorf("fragment %q has %d matches, want 1", f, strings.Count(f, MatchStart))
+		}
+		if words := len(Tokenize(f)); words > FragmentWords+1 {
+			t.Errorf("fragment %q has %d words, want at most %d", f, words, FragmentWords+1)
+		}
+	}
+}
+
+func TestRenderFragments(t *testing.T) {
+	got := RenderFragments(
+		"<b>"+MatchStart+"x"+MatchEnd+"</b>"+FragmentDelimiter+"no match",
+		"",
+		" "+MatchStart+"y"+MatchEnd+" & z ",
+	)
+	want := []string{"&lt;b&gt;<mark>x</mark>&lt;/b&gt;", "<mark>y</mark> &amp; z"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderFragments = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package search
+
+import "math"
+
+// fieldGap separates positions of fields of a document, so that phrases don't match across fields
+const fieldGap = 100
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Index is an inverted index mapping terms to their positions in documents.
+// It isn't safe for concurrent use.
+type Index struct {
+	postings map[string]map[uint][]int
+	terms    map[uint][]string // Distinct terms of every document, to remove it
+	lengths  map[uint]int
+	total    int // Sum of lengths
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[uint][]int),
+		terms:    make(map[uint][]string),
+		lengths:  make(map[uint]int),
+	}
+}
+
+// Add indexes a document made of fields, replacing the previous version of the document.
+func (ix *Index) Add(doc uint, fields ...string) {
+	tokenized := make([][]string, len(fields))
+	for i, f := range fields {
+		tokenized[i] = terms(f)
+	}
+	ix.AddTerms(doc, tokenized...)
+}
+
+// AddTerms is Add for documents tokenized by the caller.
+func (ix *Index) AddTerms(doc uint, fields ...[]string) {
+	ix.Remove(doc)
+
+	pos, length := 0, 0
+	for _, f := range fields {
+		for _, t := range f {
+			docs, ok := ix.postings[t]
+			if !ok {
+				docs = make(map[uint][]int)
+				ix.postings[t] = docs
+			}
+			if len(docs[doc]) == 0 {
+				ix.terms[doc] = append(ix.terms[doc], t)
+			}
+			docs[doc] = append(docs[doc], pos)
+			pos++
+			length++
+		}
+		pos += fieldGap
+	}
+
+	ix.lengths[doc] = length
+	ix.total += length
+}
+
+func (ix *Index) Remove(doc uint) {
+	for _, t := range ix.terms[doc] {
+		delete(ix.postings[t], doc)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	ix.total -= ix.lengths[doc]
+	delete(ix.terms, doc)
+	delete(ix.lengths, doc)
+}
+
+// Search returns BM25 scores of the documents matching q.
+func (ix *Index) Search(q Query) map[uint]float64 {
+	res := make(map[uint]float64)
+	if q.IsEmpty() {
+		return res
+	}
+
+	for doc := range ix.candidates(q.Phrases[0]) {
+		if ix.matches(doc, q) {
+			res[doc] = ix.score(doc, q)
+		}
+	}
+	return res
+}
+
+// candidates returns documents containing every term of phrase.
+func (ix *Index) candidates(phrase []string) map[uint][]int {
+	rarest := ix.postings[phrase[0]]
+	for _, t := range phrase[1:] {
+		if len(ix.postings[t]) < len(rarest) {
+			rarest = ix.postings[t]
+		}
+	}
+	return rarest
+}
+
+func (ix *Index) matches(doc uint, q Query) bool {
+	for _, p := range q.Phrases {
+		if !ix.contains(doc, p) {
+			return false
+		}
+	}
+	for _, p := range q.Excluded {
+		if ix.contains(doc, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether the terms of phrase go one after another in doc.
+func (ix *Index) contains(doc uint, phrase []string) bool {
+	positions := make([]map[int]bool, len(phrase))
+	for i, t := range phrase {
+		p := ix.postings[t][doc]
+		if len(p) == 0 {
+			return false
+		}
+		positions[i] = make(map[int]bool, len(p))
+		for _, pos := range p {
+			positions[i][pos] = true
+		}
+	}
+
+next:
+	for start := range positions[0] {
+		for i := 1; i < len(phrase); i++ {
+			if !positions[i][start+i] {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (ix *Index) score(doc uint, q Query) float64 {
+	n := float64(len(ix.lengths))
+	avg := float64(ix.total) / n
+	length := float64(ix.lengths[doc])
+
+	var score float64
+	for t := range q.Terms() {
+		df := float64(len(ix.postings[t]))
+		tf := float64(len(ix.postings[t][doc]))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/avg))
+	}
+	return score
+}
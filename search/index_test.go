@@ -0,0 +1,82 @@
+package search
+
+import "testing"
+
+func TestIndexMatches(t *testing.T) {
+	ix := NewIndex()
+	ix.Add(1, "binary search", "a binary search over a sorted slice")
+	ix.Add(2, "tree", "binary trees and how to search them")
+	ix.Add(3, "search", "red black tree")
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{"binary", []uint{1, 2}},
+		{"binary search", []uint{1, 2}},
+		{`"binary search"`, []uint{1}},
+		{"search -binary", []uint{3}},
+		{`tree -"red black"`, []uint{2}},
+		{"missing", nil},
+		{"binary missing", nil},
+		{"-binary", nil},
+		// Phrases don't span fields
+		{`"search a"`, nil},
+	}
+	for _, test := range tests {
+		got := ix.Search(ParseQuery(test.query))
+		if len(got) != len(test.want) {
+			t.Errorf("%q finds %v, want %v", test.query, got, test.want)
+			continue
+		}
+		for _, doc := range test.want {
+			if _, ok := got[doc]; !ok {
+				t.Errorf("%q finds %v, want %v", test.query, got, test.want)
+			}
+		}
+	}
+}
+
+func TestIndexRanking(t *testing.T) {
+	ix := NewIndex()
+	ix.Add(1, "quicksort", "sorting with quicksort, quicksort again")
+	ix.Add(2, "sorting", "a long text about many ways of sorting, quicksort among them")
+	ix.Add(3, "merge sort", "sorting by merging")
+
+	scores := ix.Search(ParseQuery("quicksort"))
+	if scores[1] <= scores[2] {
+		t.Errorf("scores %v, want the document mentioning quicksort more often first", scores)
+	}
+	// Rare terms weigh more than common ones
+	scores = ix.Search(ParseQuery("sorting merging"))
+	if len(scores) != 1 || scores[3] <= 0 {
+		t.Errorf("scores %v, want document 3 alone", scores)
+	}
+	common, rare := ix.Search(ParseQuery("sorting")), ix.Search(ParseQuery("merging"))
+	if rare[3] <= common[3] {
+		t.Errorf("merging scores %v and sorting scores %v, want the rare term to weigh more", rare[3], common[3])
+	}
+}
+
+func TestIndexReplacesAndRemovesDocuments(t *testing.T) {
+	ix := NewIndex()
+	ix.Add(1, "old contents")
+	ix.Add(2, "other contents")
+	ix.Add(1, "new contents")
+
+	if got := ix.Search(ParseQuery("old")); len(got) != 0 {
+		t.Errorf("old version still found: %v", got)
+	}
+	if got := ix.Search(ParseQuery("new")); len(got) != 1 {
+		t.Errorf("new version found as %v", got)
+	}
+
+	ix.Remove(1)
+	if got := ix.Search(ParseQuery("contents")); len(got) != 1 || got[2] <= 0 {
+		t.Errorf("contents found in %v after removing document 1", got)
+	}
+	ix.Remove(2)
+	if len(ix.postings) != 0 || ix.total != 0 {
+		t.Errorf("%d terms and %d words left after removing every document", len(ix.postings), ix.total)
+	}
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token is a term of a text along with its byte offsets in the text.
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into lowercased runs of letters and digits.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, Token{Term: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Term: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+func terms(text string) []string {
+	tokens := Tokenize(text)
+	res := make([]string, len(tokens))
+	for i, t := range tokens {
+		res[i] = t.Term
+	}
+	return res
+}
+
+// Query matches documents containing every phrase and none of the excluded ones.
+// Single words are one-word phrases.
+type Query struct {
+	Phrases  [][]string
+	Excluded [][]string
+}
+
+// ParseQuery understands the part of the websearch_to_tsquery syntax the
+// Postgres storage uses: words, "quoted phrases" and -excluded words or phrases.
+func ParseQuery(s string) Query {
+	var q Query
+	for s != "" {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		exclude := strings.HasPrefix(s, "-")
+		if exclude {
+			s = s[1:]
+		}
+
+		var phrase string
+		if strings.HasPrefix(s, "\"") {
+			s = s[1:]
+			end := strings.IndexByte(s, '"')
+			if end < 0 {
+				end = len(s)
+			}
+			phrase, s = s[:end], strings.TrimPrefix(s[end:], "\"")
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			phrase, s = s[:end], s[end:]
+		}
+
+		t := terms(phrase)
+		if len(t) == 0 {
+			continue
+		}
+		// Words like snake_case split into several terms, so they are phrases too
+		if exclude {
+			q.Excluded = append(q.Excluded, t)
+		} else {
+			q.Phrases = append(q.Phrases, t)
+		}
+	}
+	return q
+}
+
+// IsEmpty reports whether the query has nothing to look for.
+func (q Query) IsEmpty() bool {
+	return len(q.Phrases) == 0
+}
+
+// Terms returns the distinct terms the matching documents contain.
+func (q Query) Terms() map[string]bool {
+	res := make(map[string]bool)
+	for _, p := range q.Phrases {
+		for _, t := range p {
+			res[t] = true
+		}
+	}
+	return res
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Token
+	}{
+		{"", nil},
+		{"  \n", nil},
+		{"Hello, World!", []Token{{"hello", 0, 5}, {"world", 7, 12}}},
+		{"snake_case x2", []Token{{"snake", 0, 5}, {"case", 6, 10}, {"x2", 11, 13}}},
+		{"Привет мир", []Token{{"привет", 0, 12}, {"мир", 13, 19}}},
+		{"a+b", []Token{{"a", 0, 1}, {"b", 2, 3}}},
+	}
+	for _, test := range tests {
+		if got := Tokenize(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Query
+	}{
+		{"", Query{}},
+		{"  !! ", Query{}},
+		{"Binary Search", Query{Phrases: [][]string{{"binary"}, {"search"}}}},
+		{`"binary search" tree`, Query{Phrases: [][]string{{"binary", "search"}, {"tree"}}}},
+		{"tree -binary", Query{Phrases: [][]string{{"tree"}}, Excluded: [][]string{{"binary"}}}},
+		{`tree -"red black"`, Query{Phrases: [][]string{{"tree"}}, Excluded: [][]string{{"red", "black"}}}},
+		{"parse_request", Query{Phrases: [][]string{{"parse", "request"}}}},
+		{`"unclosed phrase`, Query{Phrases: [][]string{{"unclosed", "phrase"}}}},
+		{"-only", Query{Excluded: [][]string{{"only"}}}},
+	}
+	for _, test := range tests {
+		if got := ParseQuery(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestQueryWithOnlyExclusionsIsEmpty(t *testing.T) {
+	if q := ParseQuery("-only -excluded"); !q.IsEmpty() {
+		t.Errorf("%+v isn't empty", q)
+	}
+	if q := ParseQuery("word"); q.IsEmpty() {
+		t.Errorf("%+v is empty", q)
+	}
+}
@@ -0,0 +1,197 @@
+package search
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// TrigramIndex maps every three bytes of lowercased documents to the documents
+// containing them, to prefilter documents a regular expression may match.
+// It isn't safe for concurrent use.
+type TrigramIndex struct {
+	postings map[string]map[uint]bool
+	trigrams map[uint][]string // Distinct trigrams of every document, to remove it
+}
+
+func NewTrigramIndex() *TrigramIndex {
+	return &TrigramIndex{
+		postings: make(map[string]map[uint]bool),
+		trigrams: make(map[uint][]string),
+	}
+}
+
+func trigrams(s string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for i := 0; i+3 <= len(s); i++ {
+		if t := s[i : i+3]; !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// Add indexes a document, replacing the previous version of the document.
+func (ix *TrigramIndex) Add(doc uint, text string) {
+	ix.Remove(doc)
+	ix.trigrams[doc] = trigrams(strings.ToLower(text))
+	for _, t := range ix.trigrams[doc] {
+		docs, ok := ix.postings[t]
+		if !ok {
+			docs = make(map[uint]bool)
+			ix.postings[t] = docs
+		}
+		docs[doc] = true
+	}
+}
+
+func (ix *TrigramIndex) Remove(doc uint) {
+	for _, t := range ix.trigrams[doc] {
+		delete(ix.postings[t], doc)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.trigrams, doc)
+}
+
+// Candidates returns documents that may match the regular expression pattern.
+// If the pattern has no literals to look for, every document may match and
+// Candidates returns nil and true.
+func (ix *TrigramIndex) Candidates(pattern string) (map[uint]bool, bool, error) {
+	f, err := RegexpPrefilter(pattern)
+	if err != nil {
+		return nil, false, err
+	}
+	docs, all := ix.eval(f)
+	return docs, all, nil
+}
+
+type PrefilterOp int
+
+const (
+	PrefilterAll PrefilterOp = iota // Any document may match
+	PrefilterAnd
+	PrefilterOr
+)
+
+// Prefilter is a boolean formula over lowercased literals, at least three bytes
+// long each, which documents must contain to match a regular expression.
+// Documents satisfying it may still not match, it only rules out the ones
+// that can't.
+type Prefilter struct {
+	Op       PrefilterOp
+	Literals []string // Literals of PrefilterAnd
+	Subs     []Prefilter
+}
+
+// RegexpPrefilter builds a prefilter from the literals the regular expression
+// pattern can't match without, along the lines of Russ Cox's "Regular Expression
+// Matching with a Trigram Index".
+func RegexpPrefilter(pattern string) (Prefilter, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return Prefilter{}, err
+	}
+	return analyze(re.Simplify()), nil
+}
+
+func analyze(re *syntax.Regexp) Prefilter {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if len(string(re.Rune)) < 3 {
+			return Prefilter{Op: PrefilterAll}
+		}
+		return Prefilter{Op: PrefilterAnd, Literals: []string{strings.ToLower(string(re.Rune))}}
+	case syntax.OpCapture, syntax.OpPlus:
+		return analyze(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return analyze(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		f := Prefilter{Op: PrefilterAnd}
+		var literal []rune
+		flush := func() {
+			if len(string(literal)) >= 3 {
+				f.Literals = append(f.Literals, strings.ToLower(string(literal)))
+			}
+			literal = nil
+		}
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				literal = append(literal, sub.Rune...)
+				continue
+			}
+			flush()
+			if s := analyze(sub); s.Op != PrefilterAll {
+				f.Subs = append(f.Subs, s)
+			}
+		}
+		flush()
+		if len(f.Literals) == 0 && len(f.Subs) == 0 {
+			return Prefilter{Op: PrefilterAll}
+		}
+		return f
+	case syntax.OpAlternate:
+		f := Prefilter{Op: PrefilterOr}
+		for _, sub := range re.Sub {
+			s := analyze(sub)
+			if s.Op == PrefilterAll {
+				return s
+			}
+			f.Subs = append(f.Subs, s)
+		}
+		return f
+	}
+	return Prefilter{Op: PrefilterAll}
+}
+
+func (ix *TrigramIndex) eval(f Prefilter) (map[uint]bool, bool) {
+	switch f.Op {
+	case PrefilterAnd:
+		var res map[uint]bool
+		intersect := func(docs map[uint]bool) {
+			if res == nil {
+				res = make(map[uint]bool, len(docs))
+				for d := range docs {
+					res[d] = true
+				}
+				return
+			}
+			for d := range res {
+				if !docs[d] {
+					delete(res, d)
+				}
+			}
+		}
+		for _, l := range f.Literals {
+			for _, t := range trigrams(l) {
+				intersect(ix.postings[t])
+			}
+		}
+		for _, s := range f.Subs {
+			if docs, all := ix.eval(s); !all {
+				intersect(docs)
+			}
+		}
+		if res == nil {
+			return nil, true
+		}
+		return res, false
+	case PrefilterOr:
+		res := make(map[uint]bool)
+		for _, s := range f.Subs {
+			docs, all := ix.eval(s)
+			if all {
+				return nil, true
+			}
+			for d := range docs {
+				res[d] = true
+			}
+		}
+		return res, false
+	}
+	return nil, true
+}
@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"github.com/mp-hl-2021/splinter/languages"
+	"github.com/mp-hl-2021/splinter/types"
+)
+
+// canonicalLanguage maps a stored language, which may predate the registry,
+// to its identifier in the registry. Languages the registry doesn't know
+// become plain text.
+func canonicalLanguage(language types.ProgrammingLanguage) types.ProgrammingLanguage {
+	if l, ok := languages.Lookup(string(language)); ok {
+		return l.Id
+	}
+	return languages.Text
+}
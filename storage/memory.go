@@ -3,15 +3,21 @@ package storage
 import (
 	"errors"
 	"github.com/mp-hl-2021/splinter/auth"
+	"github.com/mp-hl-2021/splinter/search"
 	"github.com/mp-hl-2021/splinter/types"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 var (
-	NoSuchSnippetErr = errors.New("no such snippet")
-	NoSuchCommentErr = errors.New("no such comment")
-	InvalidVoteErr   = errors.New("invalid vote")
+	NoSuchSnippetErr    = errors.New("no such snippet")
+	NoSuchCommentErr    = errors.New("no such comment")
+	NoSuchRevisionErr   = errors.New("no such revision")
+	NoSuchCollectionErr = errors.New("no such collection")
+	InvalidVoteErr      = errors.New("invalid vote")
 )
 
 type SnippetVote struct {
@@ -20,10 +26,54 @@ type SnippetVote struct {
 	Vote      int
 }
 
+type SnippetReaction struct {
+	UserId    types.UserId
+	SnippetId types.SnippetId
+	Reaction  types.Reaction
+}
+
+type CommentReaction struct {
+	UserId    types.UserId
+	CommentId types.CommentId
+	Reaction  types.Reaction
+}
+
+type CommentVote struct {
+	UserId    types.UserId
+	CommentId types.CommentId
+	Vote      int
+}
+
+type Bookmark struct {
+	UserId    types.UserId
+	SnippetId types.SnippetId
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	Follower types.UserId
+	Followee types.UserId
+}
+
 type Memory struct {
 	snippets           []types.Snippet
+	revisions          []types.Revision
+	files              map[types.SnippetId][]types.File // Files after the one in Contents
 	votes              []SnippetVote
+	reactions          []SnippetReaction
+	follows            []Follow
+	bookmarks          []Bookmark
+	collections        []types.Collection
+	collectionItems    map[types.CollectionId][]types.SnippetId
 	comments           []types.Comment
+	commentRevisions   []types.CommentRevision
+	commentVotes       []CommentVote
+	commentReactions   []CommentReaction
+	expired            map[types.SnippetId]bool
+	snippetIndex       *search.Index
+	commentIndex       *search.Index
+	identifierIndex    *search.Index
+	trigramIndex       *search.TrigramIndex
 	accountsById       map[uint]auth.Account
 	accountsByUsername map[string]auth.Account
 	nextId             uint
@@ -32,8 +82,16 @@ type Memory struct {
 
 func NewMemory() *Memory {
 	return &Memory{
+		expired:            make(map[types.SnippetId]bool),
+		collectionItems:    make(map[types.CollectionId][]types.SnippetId),
+		files:              make(map[types.SnippetId][]types.File),
+		snippetIndex:       search.NewIndex(),
+		commentIndex:       search.NewIndex(),
+		identifierIndex:    search.NewIndex(),
+		trigramIndex:       search.NewTrigramIndex(),
 		accountsById:       make(map[uint]auth.Account),
 		accountsByUsername: make(map[string]auth.Account),
+		nextId:             1, // Zero ids mean "none", e.g. in Snippet.ForkedFrom
 		mu:                 &sync.Mutex{},
 	}
 }
@@ -58,12 +116,145 @@ func (m *Memory) AddSnippet(snippet types.Snippet) (types.SnippetId, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	snippet.Id = types.SnippetId(m.nextId)
+	snippet.Revision = 1
 	snippet.CreatedAt = time.Now()
 	m.nextId++
+	files := snippet.Files
+	if len(files) == 0 {
+		files = []types.File{snippet.MainFile()}
+	}
+	m.setFiles(&snippet, files)
 	m.snippets = append(m.snippets, snippet)
+	m.indexSnippet(snippet)
+	m.addRevision(snippet, files)
 	return snippet.Id, nil
 }
 
+func (m *Memory) UpdateSnippet(snippet types.SnippetId, files []types.File) (types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, s := range m.snippets {
+		if s.Id == snippet {
+			m.setFiles(&s, files)
+			s.Revision++
+			m.snippets[i] = s
+			m.indexSnippet(s)
+			m.addRevision(s, files)
+			return s, nil
+		}
+	}
+	return types.Snippet{}, NoSuchSnippetErr
+}
+
+// setFiles puts the first file into the snippet and keeps the others aside.
+func (m *Memory) setFiles(s *types.Snippet, files []types.File) {
+	first := files[0]
+	s.Filename, s.Contents, s.HighlightedContents, s.Language = first.Name, first.Contents, first.HighlightedContents, first.Language
+	s.Files = nil
+	delete(m.files, s.Id)
+	if len(files) > 1 {
+		m.files[s.Id] = append([]types.File(nil), files[1:]...)
+	}
+}
+
+func (m *Memory) addRevision(s types.Snippet, files []types.File) {
+	revisionFiles := make([]types.File, len(files))
+	for i, f := range files {
+		revisionFiles[i] = types.File{Name: f.Name, Contents: f.Contents, HighlightedContents: f.HighlightedContents, Language: f.Language}
+	}
+	m.revisions = append(m.revisions, types.Revision{
+		Snippet:             s.Id,
+		Number:              s.Revision,
+		Contents:            s.Contents,
+		HighlightedContents: s.HighlightedContents,
+		Language:            s.Language,
+		Files:               revisionFiles,
+		CreatedAt:           time.Now(),
+	})
+}
+
+// fileContents lists contents of every file of the snippet.
+func (m *Memory) fileContents(s types.Snippet) []string {
+	res := []string{s.Contents}
+	for _, f := range m.files[s.Id] {
+		res = append(res, f.Contents)
+	}
+	return res
+}
+
+// snippetLanguages lists distinct languages of the snippet files.
+func snippetLanguages(s types.Snippet, files []types.File) []types.ProgrammingLanguage {
+	res := []types.ProgrammingLanguage{s.Language}
+	for _, f := range files {
+		seen := false
+		for _, l := range res {
+			seen = seen || l == f.Language
+		}
+		if !seen {
+			res = append(res, f.Language)
+		}
+	}
+	return res
+}
+
+// hasLanguage reports whether any file of the snippet is in the language.
+func (m *Memory) hasLanguage(s types.Snippet, language types.ProgrammingLanguage) bool {
+	if s.Language == language {
+		return true
+	}
+	for _, f := range m.files[s.Id] {
+		if f.Language == language {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) UpdateSnippetDetails(snippet types.Snippet) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, s := range m.snippets {
+		if s.Id == snippet.Id {
+			m.snippets[i].Title = snippet.Title
+			m.snippets[i].Description = snippet.Description
+			m.snippets[i].Tags = snippet.Tags
+			m.snippets[i].Visibility = snippet.Visibility
+			m.indexSnippet(m.snippets[i])
+			return nil
+		}
+	}
+	return NoSuchSnippetErr
+}
+
+func (m *Memory) GetRevisions(snippet types.SnippetId) ([]types.Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Revision
+	for _, r := range m.revisions {
+		if r.Snippet == snippet {
+			res = append(res, copyRevision(r))
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) GetRevision(snippet types.SnippetId, revision int) (types.Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.revisions {
+		if r.Snippet == snippet && r.Number == revision {
+			return copyRevision(r), nil
+		}
+	}
+	return types.Revision{}, NoSuchRevisionErr
+}
+
+// copyRevision keeps highlighting of stored revision files from leaking to callers.
+func copyRevision(r types.Revision) types.Revision {
+	r.Files = append([]types.File(nil), r.Files...)
+	return r
+}
+
 func (m *Memory) GetAccountById(id uint) (auth.Account, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -84,132 +275,1184 @@ func (m *Memory) GetAccountByUsername(username string) (auth.Account, error) {
 	return a, nil
 }
 
-func (m *Memory) GetSnippetsByUser(user types.UserId) ([]types.Snippet, error) {
+func (m *Memory) GetSnippetsByUser(user types.UserId, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var res []types.Snippet
+	return pageSnippets(m.snippets, page, func(s types.Snippet) bool {
+		return s.Author == user && listedFor(s, viewer)
+	}), nil
+}
+
+func (m *Memory) GetSnippetsByLanguage(language types.ProgrammingLanguage, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return pageSnippets(m.snippets, page, func(s types.Snippet) bool {
+		return m.hasLanguage(s, language) && listedFor(s, viewer)
+	}), nil
+}
+
+func (m *Memory) GetFeed(user types.UserId, page types.Page) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	followees := make(map[types.UserId]bool)
+	for _, f := range m.follows {
+		if f.Follower == user {
+			followees[f.Followee] = true
+		}
+	}
+
+	return pageSnippets(m.snippets, page, func(s types.Snippet) bool {
+		return followees[s.Author] && publiclyListed(s)
+	}), nil
+}
+
+func (m *Memory) GetForks(snippet types.SnippetId, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return pageSnippets(m.snippets, page, func(s types.Snippet) bool {
+		return s.ForkedFrom == snippet && listedFor(s, viewer)
+	}), nil
+}
+
+func (m *Memory) GetSnippetsByTag(tag string, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return pageSnippets(m.snippets, page, func(s types.Snippet) bool {
+		if !listedFor(s, viewer) {
+			return false
+		}
+		for _, t := range s.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (m *Memory) CountSnippetsByLanguage() (map[types.ProgrammingLanguage]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := make(map[types.ProgrammingLanguage]int)
 	for _, s := range m.snippets {
-		if s.Author == user {
-			res = append(res, s)
+		if !publiclyListed(s) {
+			continue
+		}
+		for _, l := range snippetLanguages(s, m.files[s.Id]) {
+			counts[l]++
 		}
 	}
-	return res, nil
+	return counts, nil
 }
 
-func (m *Memory) GetSnippetsByLanguage(language types.ProgrammingLanguage) ([]types.Snippet, error) {
+func (m *Memory) GetPopularTags(limit int) ([]types.TagCount, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var res []types.Snippet
+	counts := make(map[string]int)
 	for _, s := range m.snippets {
-		if s.Language == language {
-			res = append(res, s)
+		if !publiclyListed(s) {
+			continue
+		}
+		for _, t := range s.Tags {
+			counts[t]++
+		}
+	}
+
+	var res []types.TagCount
+	for t, n := range counts {
+		res = append(res, types.TagCount{Tag: t, Snippets: n})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Snippets != res[j].Snippets {
+			return res[i].Snippets > res[j].Snippets
 		}
+		return res[i].Tag < res[j].Tag
+	})
+
+	if len(res) > limit {
+		res = res[:limit]
 	}
 	return res, nil
 }
 
-func (m *Memory) GetSnippet(snippet types.SnippetId) (types.Snippet, error) {
+func (m *Memory) GetRankingCandidates(language types.ProgrammingLanguage, viewer types.UserId, since time.Time, limit int) ([]types.Snippet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	var window []types.Snippet
 	for _, s := range m.snippets {
-		if s.Id == snippet {
-			return s, nil
+		if (language == "" || m.hasLanguage(s, language)) && !s.CreatedAt.Before(since) && listedFor(s, viewer) {
+			window = append(window, s)
 		}
 	}
-	return types.Snippet{}, NoSuchSnippetErr
+
+	keys := []func(s types.Snippet) int64{
+		func(s types.Snippet) int64 { return int64(s.Rating.Likes - s.Rating.Dislikes) },
+		func(s types.Snippet) int64 { return int64(s.Rating.Likes) },
+		func(s types.Snippet) int64 { return s.CreatedAt.UnixNano() },
+	}
+	seen := make(map[types.SnippetId]bool)
+	var res []types.Snippet
+	for _, key := range keys {
+		sort.Slice(window, func(i, j int) bool {
+			if ki, kj := key(window[i]), key(window[j]); ki != kj {
+				return ki > kj
+			}
+			return window[i].Id > window[j].Id
+		})
+		for i := 0; i < len(window) && i < limit; i++ {
+			if s := window[i]; !seen[s.Id] {
+				seen[s.Id] = true
+				s.Contents, s.HighlightedContents = "", ""
+				res = append(res, s)
+			}
+		}
+	}
+	return res, nil
 }
 
-func (m *Memory) DeleteSnippet(snippet types.SnippetId) error {
+func (m *Memory) GetListedSnippets(snippets []types.SnippetId, viewer types.UserId) ([]types.Snippet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var res []types.Snippet
+	byId := make(map[types.SnippetId]types.Snippet, len(m.snippets))
 	for _, s := range m.snippets {
-		if s.Id != snippet {
+		byId[s.Id] = s
+	}
+
+	var res []types.Snippet
+	for _, id := range snippets {
+		if s, ok := byId[id]; ok && listedFor(s, viewer) {
 			res = append(res, s)
 		}
 	}
-	m.snippets = res
-	return nil
+	return res, nil
 }
 
-func (m *Memory) Vote(user types.UserId, snippet types.SnippetId, vote int) error {
+func (m *Memory) indexSnippet(s types.Snippet) {
+	code := strings.Join(m.fileContents(s), "\n")
+	m.snippetIndex.Add(uint(s.Id), s.Title, s.Description, code)
+	m.identifierIndex.AddTerms(uint(s.Id), search.IdentifierTerms(code))
+	m.trigramIndex.Add(uint(s.Id), code)
+}
+
+func (m *Memory) unindexSnippet(snippet types.SnippetId) {
+	m.snippetIndex.Remove(uint(snippet))
+	m.identifierIndex.Remove(uint(snippet))
+	m.trigramIndex.Remove(uint(snippet))
+}
+
+// SearchSnippets ranks snippets the same way as the Postgres storage: by
+// the score of the snippet itself plus half the best score of its comments.
+func (m *Memory) SearchSnippets(query types.SearchQuery, viewer types.UserId) ([]types.SearchResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if vote != -1 && vote != 0 && vote != 1 {
-		return InvalidVoteErr
+	q := search.ParseQuery(query.Text)
+	snippetScores := m.snippetIndex.Search(q)
+
+	commentScores := make(map[types.SnippetId]float64)
+	commentTexts := make(map[types.SnippetId][]string)
+	matchedComments := m.commentIndex.Search(q)
+	for _, c := range m.comments {
+		if score, ok := matchedComments[uint(c.Id)]; ok {
+			if score > commentScores[c.Snippet] {
+				commentScores[c.Snippet] = score
+			}
+			commentTexts[c.Snippet] = append(commentTexts[c.Snippet], c.Contents)
+		}
 	}
 
-	for i, v := range m.votes {
-		if v.UserId == user && v.SnippetId == snippet {
-			m.votes[i].Vote = vote
-			return nil
+	var res []types.SearchResult
+	for _, s := range m.snippets {
+		score, ok := snippetScores[uint(s.Id)]
+		commentScore, commentOk := commentScores[s.Id]
+		if !ok && !commentOk {
+			continue
 		}
+		if !listedFor(s, viewer) || query.Language != "" && !m.hasLanguage(s, query.Language) || query.Author != 0 && s.Author != query.Author {
+			continue
+		}
+
+		res = append(res, types.SearchResult{
+			Snippet: s,
+			Rank:    score + commentScore/2,
+			Fragments: search.RenderFragments(
+				search.Headline(s.Title+"\n"+s.Description+"\n"+strings.Join(m.fileContents(s), "\n"), q),
+				search.Headline(strings.Join(commentTexts[s.Id], "\n"), q),
+			),
+		})
 	}
 
-	m.votes = append(m.votes, SnippetVote{
-		UserId:    user,
-		SnippetId: snippet,
-		Vote:      vote,
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Rank != res[j].Rank {
+			return res[i].Rank > res[j].Rank
+		}
+		return res[i].Snippet.Id > res[j].Snippet.Id
 	})
 
-	return nil
+	if len(res) > query.Limit {
+		res = res[:query.Limit]
+	}
+	return res, nil
 }
 
-func (m *Memory) GetVote(user types.UserId, snippet types.SnippetId) (int, error) {
+func (m *Memory) SearchCode(query types.CodeSearchQuery, viewer types.UserId) ([]types.Snippet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	for _, v := range m.votes {
-		if v.UserId == user && v.SnippetId == snippet {
-			return v.Vote, nil
+	var matches func(s types.Snippet) bool
+	switch query.Mode {
+	case types.CodeSearchIdentifier:
+		term := search.NormalizeIdentifier(query.Pattern)
+		docs := m.identifierIndex.Search(search.Query{Phrases: [][]string{{term}}})
+		matches = func(s types.Snippet) bool {
+			_, ok := docs[uint(s.Id)]
+			return ok && m.anyFile(s, func(code string) bool {
+				return len(search.IdentifierMatches(code, query.Pattern)) > 0
+			})
+		}
+	case types.CodeSearchRegexp:
+		re, err := regexp.Compile(query.Pattern)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		docs, all, err := m.trigramIndex.Candidates(query.Pattern)
+		if err != nil {
+			return []types.Snippet{}, err
 		}
+		matches = func(s types.Snippet) bool {
+			return (all || docs[uint(s.Id)]) && m.anyFile(s, func(code string) bool {
+				return len(search.RegexpMatches(code, re)) > 0
+			})
+		}
+	default:
+		return []types.Snippet{}, types.ErrInvalidCodeSearchMode
 	}
-	return 0, nil
+
+	return pageSnippets(m.snippets, types.Page{Limit: query.Limit, Sort: types.SortNewest}, func(s types.Snippet) bool {
+		return listedFor(s, viewer) &&
+			(query.Language == "" || m.hasLanguage(s, query.Language)) &&
+			(query.Author == 0 || s.Author == query.Author) &&
+			matches(s)
+	}), nil
 }
 
-func (m *Memory) AddComment(comment types.Comment) (types.CommentId, error) {
+// anyFile reports whether contents of any file of the snippet satisfy match.
+func (m *Memory) anyFile(s types.Snippet, match func(code string) bool) bool {
+	for _, code := range m.fileContents(s) {
+		if match(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// listedFor reports whether the snippet shows up in listings for viewer
+func listedFor(s types.Snippet, viewer types.UserId) bool {
+	return s.Author == viewer && !s.ExpiredAt(time.Now()) || publiclyListed(s)
+}
+
+// publiclyListed reports whether the snippet shows up in listings for everyone.
+// Burn-after-read snippets never do, listing them would give their contents
+// away without burning them.
+func publiclyListed(s types.Snippet) bool {
+	return s.Visibility == types.VisibilityPublic && !s.BurnAfterRead && !s.ExpiredAt(time.Now())
+}
+
+// pageSnippets selects snippets matching filter that go after page.After,
+// sorted by page.Sort and truncated to page.Limit.
+func pageSnippets(snippets []types.Snippet, page types.Page, filter func(types.Snippet) bool) []types.Snippet {
+	var res []types.Snippet
+	for _, s := range snippets {
+		if filter(s) && (page.After.IsZero() || page.Sort.Before(page.After, page.Sort.SnippetCursor(s))) {
+			res = append(res, s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return page.Sort.Before(page.Sort.SnippetCursor(res[i]), page.Sort.SnippetCursor(res[j]))
+	})
+
+	if len(res) > page.Limit {
+		res = res[:page.Limit]
+	}
+	return res
+}
+
+func (m *Memory) GetSnippet(snippet types.SnippetId) (types.Snippet, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	comment.Id = types.CommentId(m.nextId)
-	comment.CreatedAt = time.Now()
-	m.nextId++
-	m.comments = append(m.comments, comment)
-	return comment.Id, nil
+	for _, s := range m.snippets {
+		if s.Id == snippet {
+			return s, nil
+		}
+	}
+	if m.expired[snippet] {
+		return types.Snippet{}, types.ErrSnippetExpired
+	}
+	return types.Snippet{}, NoSuchSnippetErr
 }
 
-func (m *Memory) GetComment(comment types.CommentId) (types.Comment, error) {
+func (m *Memory) BurnSnippet(snippet types.SnippetId) (bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	for _, c := range m.comments {
-		if c.Id == comment {
-			return c, nil
+	now := time.Now()
+	for i, s := range m.snippets {
+		if s.Id == snippet && s.BurnAfterRead && !s.ExpiredAt(now) {
+			m.snippets[i].ExpiresAt = &now
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	return types.Comment{}, NoSuchCommentErr
+func (m *Memory) DeleteExpiredSnippets() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var snippets []types.Snippet
+	for _, s := range m.snippets {
+		if s.ExpiredAt(now) {
+			m.expired[s.Id] = true
+			m.unindexSnippet(s.Id)
+		} else {
+			snippets = append(snippets, s)
+		}
+	}
+	n := len(m.snippets) - len(snippets)
+	if n == 0 {
+		return 0, nil
+	}
+	m.snippets = snippets
+	m.dropSnippets(func(s types.SnippetId) bool {
+		return m.expired[s]
+	})
+	return n, nil
 }
 
-func (m *Memory) GetComments(snippet types.SnippetId) ([]types.Comment, error) {
+func (m *Memory) SetSnippetHighlight(snippet types.SnippetId, revision int, highlight string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var res []types.Comment
-	for _, c := range m.comments {
-		if c.Snippet == snippet {
-			res = append(res, c)
+	for i, r := range m.revisions {
+		if r.Snippet == snippet && r.Number == revision {
+			m.revisions[i].HighlightedContents = highlight
+			m.revisions[i].Files[0].HighlightedContents = highlight
+		}
+	}
+	for i, s := range m.snippets {
+		if s.Id == snippet && s.Revision == revision {
+			m.snippets[i].HighlightedContents = highlight
 		}
 	}
+	return nil
+}
 
-	return res, nil
+func (m *Memory) GetFiles(snippets []types.SnippetId) (map[types.SnippetId][]types.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	files := make(map[types.SnippetId][]types.File)
+	for _, s := range snippets {
+		if f, ok := m.files[s]; ok {
+			files[s] = append([]types.File(nil), f...)
+		}
+	}
+	return files, nil
 }
 
-func (m *Memory) DeleteComment(comment types.CommentId) error {
+func (m *Memory) SetFileHighlight(snippet types.SnippetId, revision int, file int, highlight string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	var res []types.Comment
+	for i, r := range m.revisions {
+		if r.Snippet == snippet && r.Number == revision && file >= 0 && file < len(r.Files) {
+			m.revisions[i].Files[file].HighlightedContents = highlight
+		}
+	}
+	for _, s := range m.snippets {
+		if s.Id != snippet || s.Revision != revision {
+			continue
+		}
+		if f := m.files[snippet]; file >= 1 && file <= len(f) {
+			f[file-1].HighlightedContents = highlight
+		}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteSnippet(snippet types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Snippet
+	for _, s := range m.snippets {
+		if s.Id != snippet {
+			res = append(res, s)
+		}
+	}
+	m.snippets = res
+	m.unindexSnippet(snippet)
+
+	m.dropSnippets(func(s types.SnippetId) bool {
+		return s == snippet
+	})
+	return nil
+}
+
+// dropSnippets removes everything that belongs to deleted snippets, the way
+// foreign keys cascade in Postgres.
+func (m *Memory) dropSnippets(deleted func(s types.SnippetId) bool) {
+	var revisions []types.Revision
+	for _, r := range m.revisions {
+		if !deleted(r.Snippet) {
+			revisions = append(revisions, r)
+		}
+	}
+	m.revisions = revisions
+
+	var comments []types.Comment
+	removed := make(map[types.CommentId]bool)
 	for _, c := range m.comments {
-		if c.Id != comment {
-			res = append(res, c)
+		if !deleted(c.Snippet) {
+			comments = append(comments, c)
+		} else {
+			removed[c.Id] = true
+			m.commentIndex.Remove(uint(c.Id))
 		}
 	}
-	m.comments = res
+	m.comments = comments
+
+	var commentVotes []CommentVote
+	for _, v := range m.commentVotes {
+		if !removed[v.CommentId] {
+			commentVotes = append(commentVotes, v)
+		}
+	}
+	m.commentVotes = commentVotes
+
+	var commentReactions []CommentReaction
+	for _, r := range m.commentReactions {
+		if !removed[r.CommentId] {
+			commentReactions = append(commentReactions, r)
+		}
+	}
+	m.commentReactions = commentReactions
+
+	var commentRevisions []types.CommentRevision
+	for _, r := range m.commentRevisions {
+		if !removed[r.Comment] {
+			commentRevisions = append(commentRevisions, r)
+		}
+	}
+	m.commentRevisions = commentRevisions
+
+	var votes []SnippetVote
+	for _, v := range m.votes {
+		if !deleted(v.SnippetId) {
+			votes = append(votes, v)
+		}
+	}
+	m.votes = votes
+
+	var reactions []SnippetReaction
+	for _, r := range m.reactions {
+		if !deleted(r.SnippetId) {
+			reactions = append(reactions, r)
+		}
+	}
+	m.reactions = reactions
+	for s := range m.files {
+		if deleted(s) {
+			delete(m.files, s)
+		}
+	}
+	m.dropFromCollections(deleted)
+}
+
+// dropFromCollections removes deleted snippets from bookmarks and collections.
+func (m *Memory) dropFromCollections(deleted func(s types.SnippetId) bool) {
+	var bookmarks []Bookmark
+	for _, b := range m.bookmarks {
+		if !deleted(b.SnippetId) {
+			bookmarks = append(bookmarks, b)
+		}
+	}
+	m.bookmarks = bookmarks
+
+	for c, items := range m.collectionItems {
+		var res []types.SnippetId
+		for _, s := range items {
+			if !deleted(s) {
+				res = append(res, s)
+			}
+		}
+		m.collectionItems[c] = res
+	}
+}
+
+func (m *Memory) Vote(user types.UserId, snippet types.SnippetId, vote int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vote != -1 && vote != 0 && vote != 1 {
+		return InvalidVoteErr
+	}
+
+	old := 0
+	found := false
+	for i, v := range m.votes {
+		if v.UserId == user && v.SnippetId == snippet {
+			old = v.Vote
+			found = true
+			m.votes[i].Vote = vote
+			break
+		}
+	}
+
+	if !found {
+		m.votes = append(m.votes, SnippetVote{
+			UserId:    user,
+			SnippetId: snippet,
+			Vote:      vote,
+		})
+	}
+
+	delta := ratingDelta(old, vote)
+	for i, s := range m.snippets {
+		if s.Id == snippet {
+			m.snippets[i].Rating.Likes += delta.Likes
+			m.snippets[i].Rating.Dislikes += delta.Dislikes
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) GetVotes(user types.UserId, snippets []types.SnippetId) (map[types.SnippetId]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wanted := make(map[types.SnippetId]bool, len(snippets))
+	for _, s := range snippets {
+		wanted[s] = true
+	}
+
+	votes := make(map[types.SnippetId]int)
+	for _, v := range m.votes {
+		if v.UserId == user && wanted[v.SnippetId] {
+			votes[v.SnippetId] = v.Vote
+		}
+	}
+	return votes, nil
+}
+
+func (m *Memory) AddReaction(user types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := SnippetReaction{UserId: user, SnippetId: snippet, Reaction: reaction}
+	for _, old := range m.reactions {
+		if old == r {
+			return nil
+		}
+	}
+	m.reactions = append(m.reactions, r)
+	return nil
+}
+
+func (m *Memory) RemoveReaction(user types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := SnippetReaction{UserId: user, SnippetId: snippet, Reaction: reaction}
+	var res []SnippetReaction
+	for _, r := range m.reactions {
+		if r != removed {
+			res = append(res, r)
+		}
+	}
+	m.reactions = res
+	return nil
+}
+
+func (m *Memory) GetReactions(user types.UserId, snippets []types.SnippetId) (map[types.SnippetId]types.Reactions, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wanted := make(map[types.SnippetId]bool, len(snippets))
+	for _, s := range snippets {
+		wanted[s] = true
+	}
+
+	res := make(map[types.SnippetId]types.Reactions)
+	for _, r := range m.reactions {
+		if wanted[r.SnippetId] {
+			res[r.SnippetId] = countReaction(res[r.SnippetId], r.Reaction, r.UserId == user)
+		}
+	}
+	return res, nil
+}
+
+// countReaction adds a reaction to sum, mine tells whether the viewer has left it.
+func countReaction(sum types.Reactions, reaction types.Reaction, mine bool) types.Reactions {
+	if sum.Counts == nil {
+		sum.Counts = make(map[types.Reaction]int)
+	}
+	sum.Counts[reaction]++
+	if mine {
+		sum.CurrentUser = append(sum.CurrentUser, reaction)
+	}
+	return sum
+}
+
+// ReconcileRatings recomputes like/dislike counters of every snippet from
+// the stored votes and returns the number of snippets that were out of sync.
+func (m *Memory) ReconcileRatings() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ratings := make(map[types.SnippetId]types.Rating)
+	for _, v := range m.votes {
+		r := ratings[v.SnippetId]
+		d := ratingDelta(0, v.Vote)
+		r.Likes += d.Likes
+		r.Dislikes += d.Dislikes
+		ratings[v.SnippetId] = r
+	}
+
+	fixed := 0
+	for i, s := range m.snippets {
+		if s.Rating != ratings[s.Id] {
+			m.snippets[i].Rating = ratings[s.Id]
+			fixed++
+		}
+	}
+	return fixed, nil
+}
+
+// NormalizeLanguages replaces languages of snippets, their files and revisions
+// with identifiers of the registry and returns the number of snippets changed.
+func (m *Memory) NormalizeLanguages() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	changed := make(map[types.SnippetId]bool)
+	normalize := func(snippet types.SnippetId, language *types.ProgrammingLanguage) {
+		if l := canonicalLanguage(*language); l != *language {
+			*language = l
+			changed[snippet] = true
+		}
+	}
+
+	for i := range m.snippets {
+		normalize(m.snippets[i].Id, &m.snippets[i].Language)
+	}
+	for snippet, files := range m.files {
+		for i := range files {
+			normalize(snippet, &files[i].Language)
+		}
+	}
+	for i := range m.revisions {
+		r := &m.revisions[i]
+		normalize(r.Snippet, &r.Language)
+		for j := range r.Files {
+			normalize(r.Snippet, &r.Files[j].Language)
+		}
+	}
+	return len(changed), nil
+}
+
+func (m *Memory) GetVote(user types.UserId, snippet types.SnippetId) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range m.votes {
+		if v.UserId == user && v.SnippetId == snippet {
+			return v.Vote, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *Memory) AddComment(comment types.Comment) (types.CommentId, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	comment.Id = types.CommentId(m.nextId)
+	comment.CreatedAt = time.Now()
+	m.nextId++
+	m.comments = append(m.comments, comment)
+	m.commentIndex.Add(uint(comment.Id), comment.Contents)
+	return comment.Id, nil
+}
+
+func (m *Memory) GetComment(comment types.CommentId) (types.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.comments {
+		if c.Id == comment {
+			return c, nil
+		}
+	}
+
+	return types.Comment{}, NoSuchCommentErr
+}
+
+func (m *Memory) GetComments(snippet types.SnippetId, page types.Page) ([]types.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Comment
+	for _, c := range m.comments {
+		if c.Snippet == snippet && c.ParentId == 0 && (page.After.IsZero() || page.Sort.Before(page.After, page.Sort.CommentCursor(c))) {
+			res = append(res, c)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return page.Sort.Before(page.Sort.CommentCursor(res[i]), page.Sort.CommentCursor(res[j]))
+	})
+
+	if len(res) > page.Limit {
+		res = res[:page.Limit]
+	}
+	return res, nil
+}
+
+func (m *Memory) GetLineComments(snippet types.SnippetId) ([]types.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Comment
+	for _, c := range m.comments {
+		if c.Snippet == snippet && c.ParentId == 0 && !c.Lines.IsZero() {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) GetReplies(comments []types.CommentId) ([]types.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	thread := make(map[types.CommentId]bool, len(comments))
+	for _, c := range comments {
+		thread[c] = true
+	}
+
+	// Replies go after their parents, so one pass in creation order finds every descendant
+	var res []types.Comment
+	for _, c := range m.comments {
+		if thread[c.ParentId] {
+			thread[c.Id] = true
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) DeleteComment(comment types.CommentId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Comment
+	for _, c := range m.comments {
+		if c.Id != comment {
+			res = append(res, c)
+		}
+	}
+	m.comments = res
+	m.commentIndex.Remove(uint(comment))
+
+	var votes []CommentVote
+	for _, v := range m.commentVotes {
+		if v.CommentId != comment {
+			votes = append(votes, v)
+		}
+	}
+	m.commentVotes = votes
+
+	var reactions []CommentReaction
+	for _, r := range m.commentReactions {
+		if r.CommentId != comment {
+			reactions = append(reactions, r)
+		}
+	}
+	m.commentReactions = reactions
+	return nil
+}
+
+func (m *Memory) UpdateComment(comment types.CommentId, contents, rendered string) (types.Comment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, c := range m.comments {
+		if c.Id == comment {
+			written := c.CreatedAt
+			if c.EditedAt != nil {
+				written = *c.EditedAt
+			}
+			m.commentRevisions = append(m.commentRevisions, types.CommentRevision{
+				Comment:   c.Id,
+				Contents:  c.Contents,
+				CreatedAt: written,
+			})
+
+			now := time.Now()
+			c.Contents = contents
+			c.Rendered = rendered
+			c.EditedAt = &now
+			m.comments[i] = c
+			m.commentIndex.Add(uint(c.Id), c.Contents)
+			return c, nil
+		}
+	}
+	return types.Comment{}, NoSuchCommentErr
+}
+
+func (m *Memory) GetCommentHistory(comment types.CommentId) ([]types.CommentRevision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.CommentRevision
+	for _, r := range m.commentRevisions {
+		if r.Comment == comment {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) TombstoneComment(comment types.CommentId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, c := range m.comments {
+		if c.Id == comment {
+			m.comments[i].Deleted = true
+			m.comments[i].Contents = ""
+			m.comments[i].Rendered = ""
+		}
+	}
+	m.commentIndex.Remove(uint(comment))
+	return nil
+}
+
+func (m *Memory) VoteComment(user types.UserId, comment types.CommentId, vote int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vote != -1 && vote != 0 && vote != 1 {
+		return InvalidVoteErr
+	}
+
+	old := 0
+	found := false
+	for i, v := range m.commentVotes {
+		if v.UserId == user && v.CommentId == comment {
+			old = v.Vote
+			found = true
+			m.commentVotes[i].Vote = vote
+			break
+		}
+	}
+
+	if !found {
+		m.commentVotes = append(m.commentVotes, CommentVote{
+			UserId:    user,
+			CommentId: comment,
+			Vote:      vote,
+		})
+	}
+
+	delta := ratingDelta(old, vote)
+	for i, c := range m.comments {
+		if c.Id == comment {
+			m.comments[i].Rating.Likes += delta.Likes
+			m.comments[i].Rating.Dislikes += delta.Dislikes
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) GetCommentVotes(user types.UserId, comments []types.CommentId) (map[types.CommentId]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wanted := make(map[types.CommentId]bool, len(comments))
+	for _, c := range comments {
+		wanted[c] = true
+	}
+
+	votes := make(map[types.CommentId]int)
+	for _, v := range m.commentVotes {
+		if v.UserId == user && wanted[v.CommentId] {
+			votes[v.CommentId] = v.Vote
+		}
+	}
+	return votes, nil
+}
+
+func (m *Memory) AddCommentReaction(user types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := CommentReaction{UserId: user, CommentId: comment, Reaction: reaction}
+	for _, old := range m.commentReactions {
+		if old == r {
+			return nil
+		}
+	}
+	m.commentReactions = append(m.commentReactions, r)
+	return nil
+}
+
+func (m *Memory) RemoveCommentReaction(user types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := CommentReaction{UserId: user, CommentId: comment, Reaction: reaction}
+	var res []CommentReaction
+	for _, r := range m.commentReactions {
+		if r != removed {
+			res = append(res, r)
+		}
+	}
+	m.commentReactions = res
+	return nil
+}
+
+func (m *Memory) GetCommentReactions(user types.UserId, comments []types.CommentId) (map[types.CommentId]types.Reactions, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wanted := make(map[types.CommentId]bool, len(comments))
+	for _, c := range comments {
+		wanted[c] = true
+	}
+
+	res := make(map[types.CommentId]types.Reactions)
+	for _, r := range m.commentReactions {
+		if wanted[r.CommentId] {
+			res[r.CommentId] = countReaction(res[r.CommentId], r.Reaction, r.UserId == user)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) Follow(follower types.UserId, followee types.UserId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range m.follows {
+		if f.Follower == follower && f.Followee == followee {
+			return nil
+		}
+	}
+
+	m.follows = append(m.follows, Follow{
+		Follower: follower,
+		Followee: followee,
+	})
+
+	return nil
+}
+
+func (m *Memory) Unfollow(follower types.UserId, followee types.UserId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []Follow
+	for _, f := range m.follows {
+		if f.Follower != follower || f.Followee != followee {
+			res = append(res, f)
+		}
+	}
+	m.follows = res
+	return nil
+}
+
+func (m *Memory) IsFollowing(follower types.UserId, followee types.UserId) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range m.follows {
+		if f.Follower == follower && f.Followee == followee {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *Memory) GetFollowers(user types.UserId) ([]types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.User
+	for _, f := range m.follows {
+		if f.Followee == user {
+			a := m.accountsById[uint(f.Follower)]
+			res = append(res, types.User{Id: f.Follower, Username: a.Username})
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) GetFollowing(user types.UserId) ([]types.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.User
+	for _, f := range m.follows {
+		if f.Follower == user {
+			a := m.accountsById[uint(f.Followee)]
+			res = append(res, types.User{Id: f.Followee, Username: a.Username})
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) AddBookmark(user types.UserId, snippet types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, b := range m.bookmarks {
+		if b.UserId == user && b.SnippetId == snippet {
+			return nil
+		}
+	}
+
+	m.bookmarks = append(m.bookmarks, Bookmark{
+		UserId:    user,
+		SnippetId: snippet,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (m *Memory) RemoveBookmark(user types.UserId, snippet types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []Bookmark
+	for _, b := range m.bookmarks {
+		if b.UserId != user || b.SnippetId != snippet {
+			res = append(res, b)
+		}
+	}
+	m.bookmarks = res
+	return nil
+}
+
+func (m *Memory) GetBookmarks(user types.UserId) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []types.SnippetId
+	for i := len(m.bookmarks) - 1; i >= 0; i-- {
+		if m.bookmarks[i].UserId == user {
+			ids = append(ids, m.bookmarks[i].SnippetId)
+		}
+	}
+	return m.visibleSnippets(ids, user), nil
+}
+
+// visibleSnippets looks up snippets in the given order, leaving out
+// the ones the viewer may not open, burn-after-read snippets of others
+// and expired ones.
+func (m *Memory) visibleSnippets(ids []types.SnippetId, viewer types.UserId) []types.Snippet {
+	byId := make(map[types.SnippetId]types.Snippet, len(m.snippets))
+	for _, s := range m.snippets {
+		byId[s.Id] = s
+	}
+
+	var res []types.Snippet
+	now := time.Now()
+	for _, id := range ids {
+		if s, ok := byId[id]; ok && s.VisibleTo(viewer) && (!s.BurnAfterRead || s.Author == viewer) && !s.ExpiredAt(now) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func (m *Memory) AddCollection(collection types.Collection) (types.CollectionId, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	collection.Id = types.CollectionId(m.nextId)
+	collection.Snippets = nil
+	collection.CreatedAt = time.Now()
+	m.nextId++
+	m.collections = append(m.collections, collection)
+	return collection.Id, nil
+}
+
+func (m *Memory) GetCollection(collection types.CollectionId) (types.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.collections {
+		if c.Id == collection {
+			return c, nil
+		}
+	}
+	return types.Collection{}, NoSuchCollectionErr
+}
+
+func (m *Memory) GetCollections(owner types.UserId) ([]types.Collection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Collection
+	for _, c := range m.collections {
+		if c.Owner == owner {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (m *Memory) UpdateCollection(collection types.Collection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, c := range m.collections {
+		if c.Id == collection.Id {
+			m.collections[i].Name = collection.Name
+			m.collections[i].Public = collection.Public
+			return nil
+		}
+	}
+	return NoSuchCollectionErr
+}
+
+func (m *Memory) DeleteCollection(collection types.CollectionId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.Collection
+	for _, c := range m.collections {
+		if c.Id != collection {
+			res = append(res, c)
+		}
+	}
+	m.collections = res
+	delete(m.collectionItems, collection)
+	return nil
+}
+
+func (m *Memory) AddToCollection(collection types.CollectionId, snippet types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.collectionItems[collection] {
+		if s == snippet {
+			return nil
+		}
+	}
+	m.collectionItems[collection] = append(m.collectionItems[collection], snippet)
+	return nil
+}
+
+func (m *Memory) RemoveFromCollection(collection types.CollectionId, snippet types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []types.SnippetId
+	for _, s := range m.collectionItems[collection] {
+		if s != snippet {
+			res = append(res, s)
+		}
+	}
+	m.collectionItems[collection] = res
+	return nil
+}
+
+func (m *Memory) GetCollectionItems(collection types.CollectionId) ([]types.SnippetId, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]types.SnippetId(nil), m.collectionItems[collection]...), nil
+}
+
+func (m *Memory) GetCollectionSnippets(collection types.CollectionId, viewer types.UserId) ([]types.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.visibleSnippets(m.collectionItems[collection], viewer), nil
+}
+
+func (m *Memory) ReorderCollection(collection types.CollectionId, snippets []types.SnippetId) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	position := make(map[types.SnippetId]int, len(snippets))
+	for i, s := range snippets {
+		position[s] = i
+	}
+
+	items := m.collectionItems[collection]
+	sort.SliceStable(items, func(i, j int) bool {
+		return position[items[i]] < position[items[j]]
+	})
 	return nil
 }
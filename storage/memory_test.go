@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"math/rand"
+	"testing"
+)
+
+// BenchmarkFillVotes compares looking up votes of a page of snippets one by
+// one, as listings used to, with looking them up in a batch.
+func BenchmarkFillVotes(b *testing.B) {
+	const snippets, users, votesPerUser = 2000, 200, 50
+	m := NewMemory()
+	var page []types.SnippetId
+	for i := 0; i < snippets; i++ {
+		id, err := m.AddSnippet(types.Snippet{Author: 1, Contents: "x", Language: "go", Visibility: types.VisibilityPublic})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(page) < types.DefaultPageLimit {
+			page = append(page, id)
+		}
+	}
+	r := rand.New(rand.NewSource(1))
+	for u := 1; u <= users; u++ {
+		for i := 0; i < votesPerUser; i++ {
+			if err := m.Vote(types.UserId(u), types.SnippetId(1+r.Intn(snippets)), r.Intn(3)-1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	viewer := types.UserId(1 + r.Intn(users))
+
+	b.Run("GetVote", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, id := range page {
+				if _, err := m.GetVote(viewer, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("GetVotes", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := m.GetVotes(viewer, page); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -2,191 +2,1248 @@ package storage
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/mp-hl-2021/splinter/auth"
+	"github.com/mp-hl-2021/splinter/search"
 	"github.com/mp-hl-2021/splinter/types"
+	"regexp"
+	"strings"
+	"time"
 )
 
 type Postgres struct {
 	db *sqlx.DB
 }
 
+const snippetColumns = `id, title, description, tags, visibility, filename, contents, highlighted, language, author, likes, dislikes, revision, coalesce(forkedFrom, 0), expiresAt, burnOnRead, createdAt`
+
+// candidateColumns are snippetColumns with empty contents, for listings that don't show them
+const candidateColumns = `id, title, description, tags, visibility, filename, ''::varchar, ''::varchar, language, author, likes, dislikes, revision, coalesce(forkedFrom, 0), expiresAt, burnOnRead, createdAt`
+
+const notExpired = `(expiresAt is null or expiresAt > now())`
+
+// listedForAll filters listings down to the snippets everyone sees there.
+// Burn-after-read snippets are left out, listing them would give their
+// contents away without burning them.
+const listedForAll = `visibility = 'public' and not burnOnRead and ` + notExpired
+
+// listedFor2 filters listings down to public snippets and snippets of the viewer passed as $2
+const listedFor2 = `(visibility = 'public' and not burnOnRead or author = $2) and ` + notExpired
+
+// visibleTo2 filters snippets down to the ones the viewer passed as $2 may
+// open, leaving out burn-after-read snippets of others
+const visibleTo2 = `(visibility <> 'private' and not burnOnRead or author = $2) and ` + notExpired
+
+// allCode is contents of every file of a snippet, the trigram index is built on it
+const allCode = `(contents || E'\n' || otherContents)`
+
+// hasLanguage is the condition that a file of the snippet is in the language of parameter n
+func hasLanguage(n int) string {
+	return fmt.Sprintf(`(language = $%d or exists (select 1 from file where file.snippet = snippet.id and file.language = $%d))`, n, n)
+}
+
+const collectionColumns = `id, owner, name, public, createdAt`
+
+const commentColumns = `id, coalesce(parent, 0), depth, contents, rendered, snippet, revision, file, coalesce(lineFrom, 0), coalesce(lineTo, 0), author, likes, dislikes, deleted, createdAt, editedAt`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// snippetFields lists scan destinations for snippetColumns
+func snippetFields(s *types.Snippet) []interface{} {
+	return []interface{}{&s.Id, &s.Title, &s.Description, pq.Array(&s.Tags), &s.Visibility, &s.Filename, &s.Contents, &s.HighlightedContents, &s.Language, &s.Author, &s.Rating.Likes, &s.Rating.Dislikes, &s.Revision, &s.ForkedFrom, &s.ExpiresAt, &s.BurnAfterRead, &s.CreatedAt}
+}
+
+func scanSnippet(row rowScanner) (types.Snippet, error) {
+	var s types.Snippet
+	err := row.Scan(snippetFields(&s)...)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+	return s, nil
+}
+
+// keyset renders the tail of a listing query ordered by key: the condition
+// skipping everything up to page.After, the ordering and the limit.
+// Its placeholders are numbered starting from $n.
+func keyset(key string, after interface{}, page types.Page, n int) (string, []interface{}) {
+	cmp, dir := "<", "desc"
+	if !page.Sort.Descending() {
+		cmp, dir = ">", "asc"
+	}
+	tail := fmt.Sprintf(`and ($%d::boolean or (%s, id) %s ($%d, $%d))
+order by %s %s, id %s
+limit $%d
+`, n, key, cmp, n+1, n+2, key, dir, dir, n+3)
+	return tail, []interface{}{page.After.IsZero(), after, page.After.Id, page.Limit}
+}
+
+func snippetKeyset(page types.Page, n int) (string, []interface{}) {
+	if page.Sort == types.SortTop {
+		return keyset("likes - dislikes", page.After.Key, page, n)
+	}
+	return keyset("createdAt", page.After.Time(), page, n)
+}
+
+func commentKeyset(page types.Page, n int) (string, []interface{}) {
+	if page.Sort == types.SortTop {
+		return keyset("likes - dislikes", page.After.Key, page, n)
+	}
+	return keyset("createdAt", page.After.Time(), page, n)
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func scanComment(row rowScanner) (types.Comment, error) {
+	var c types.Comment
+	err := row.Scan(&c.Id, &c.ParentId, &c.Depth, &c.Contents, &c.Rendered, &c.Snippet, &c.Revision, &c.File, &c.Lines.From, &c.Lines.To, &c.Author, &c.Rating.Likes, &c.Rating.Dislikes, &c.Deleted, &c.CreatedAt, &c.EditedAt)
+	if err != nil {
+		return types.Comment{}, err
+	}
+	return c, nil
+}
+
+func scanComments(rows *sql.Rows) ([]types.Comment, error) {
+	defer rows.Close()
+
+	var res []types.Comment
+
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return []types.Comment{}, err
+		}
+		res = append(res, c)
+	}
+
+	return res, rows.Err()
+}
+
+func scanSnippets(rows *sql.Rows) ([]types.Snippet, error) {
+	defer rows.Close()
+
+	var res []types.Snippet
+
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		res = append(res, s)
+	}
+
+	return res, rows.Err()
+}
+
 func NewPostgres(connStr string) (Postgres, error) {
 	db, err := sqlx.Connect("postgres", connStr)
 	if err != nil {
-		return Postgres{}, err
+		return Postgres{}, err
+	}
+
+	return Postgres{db}, nil
+}
+
+func (p Postgres) Close() error {
+	return p.db.Close()
+}
+
+// otherFiles holds the files after the first one as arrays to unnest
+type otherFiles struct {
+	names, contents, languages, highlights []string
+}
+
+func newOtherFiles(files []types.File) otherFiles {
+	var o otherFiles
+	if len(files) > 1 {
+		for _, f := range files[1:] {
+			o.names = append(o.names, f.Name)
+			o.contents = append(o.contents, f.Contents)
+			o.languages = append(o.languages, string(f.Language))
+			o.highlights = append(o.highlights, f.HighlightedContents)
+		}
+	}
+	return o
+}
+
+func (o otherFiles) args() []interface{} {
+	return []interface{}{pq.Array(o.names), pq.Array(o.contents), pq.Array(o.languages), pq.Array(o.highlights)}
+}
+
+// joined is what goes to otherContents of the snippet
+func (o otherFiles) joined() string {
+	return strings.Join(o.contents, "\n")
+}
+
+func (p Postgres) AddSnippet(snippet types.Snippet) (types.SnippetId, error) {
+	files := snippet.Files
+	if len(files) == 0 {
+		files = []types.File{snippet.MainFile()}
+	}
+	other := newOtherFiles(files)
+	identifiers := search.IdentifierTerms(snippet.Contents + "\n" + other.joined())
+
+	var id int
+	err := p.db.QueryRow(`
+with s as (
+    insert into snippet (title, description, tags, visibility, filename, contents, language, otherContents, author, forkedFrom, expiresAt, burnOnRead, identifiers, createdAt) values
+    ($1, $2, coalesce($3::varchar[], '{}'), $4, $5, $6, $7, $8, $9, nullif($10, 0), $11, $12, coalesce($13::varchar[], '{}'), now()) returning id, revision, filename, contents, language, createdAt
+), f as (
+    insert into file (snippet, position, name, contents, language, highlighted)
+    select s.id, f.position, f.name, f.contents, f.language, f.highlighted
+    from s, unnest($14::varchar[], $15::varchar[], $16::varchar[], $17::varchar[]) with ordinality as f (name, contents, language, highlighted, position)
+), r as (
+    insert into revision (snippet, number, filename, contents, language, createdAt)
+    select id, revision, filename, contents, language, createdAt from s returning snippet, number
+), rf as (
+    insert into revision_file (snippet, number, position, name, contents, language, highlighted)
+    select r.snippet, r.number, f.position, f.name, f.contents, f.language, f.highlighted
+    from r, unnest($14::varchar[], $15::varchar[], $16::varchar[], $17::varchar[]) with ordinality as f (name, contents, language, highlighted, position)
+)
+select id from s;
+`, append([]interface{}{snippet.Title, snippet.Description, pq.Array(snippet.Tags), snippet.Visibility, snippet.Filename, snippet.Contents, snippet.Language, other.joined(),
+		snippet.Author, snippet.ForkedFrom, utc(snippet.ExpiresAt), snippet.BurnAfterRead, pq.Array(identifiers)},
+		other.args()...)...).Scan(&id)
+	if err != nil {
+		return types.SnippetId(0), err
+	}
+
+	return types.SnippetId(id), nil
+}
+
+// UpdateSnippet replaces every file of the snippet and saves them as a new revision.
+func (p Postgres) UpdateSnippet(snippet types.SnippetId, files []types.File) (types.Snippet, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return types.Snippet{}, err
+	}
+	defer tx.Rollback()
+
+	first, other := files[0], newOtherFiles(files)
+	identifiers := search.IdentifierTerms(first.Contents + "\n" + other.joined())
+	s, err := scanSnippet(tx.QueryRow(`
+update snippet set filename = $1, contents = $2, language = $3, highlighted = $4, otherContents = $5, revision = revision + 1, identifiers = coalesce($7::varchar[], '{}')
+where id = $6 returning `+snippetColumns+`
+`, first.Name, first.Contents, first.Language, first.HighlightedContents, other.joined(), snippet, pq.Array(identifiers)))
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	if _, err := tx.Exec(`
+delete from file where snippet = $1
+`, s.Id); err != nil {
+		return types.Snippet{}, err
+	}
+
+	if _, err := tx.Exec(`
+with f as (
+    insert into file (snippet, position, name, contents, language, highlighted)
+    select $1, f.position, f.name, f.contents, f.language, f.highlighted
+    from unnest($7::varchar[], $8::varchar[], $9::varchar[], $10::varchar[]) with ordinality as f (name, contents, language, highlighted, position)
+), r as (
+    insert into revision (snippet, number, filename, contents, highlighted, language, createdAt) values ($1, $2, $3, $4, $5, $6, now())
+)
+insert into revision_file (snippet, number, position, name, contents, language, highlighted)
+select $1, $2, f.position, f.name, f.contents, f.language, f.highlighted
+from unnest($7::varchar[], $8::varchar[], $9::varchar[], $10::varchar[]) with ordinality as f (name, contents, language, highlighted, position)
+`, append([]interface{}{s.Id, s.Revision, s.Filename, s.Contents, s.HighlightedContents, s.Language}, other.args()...)...); err != nil {
+		return types.Snippet{}, err
+	}
+
+	return s, tx.Commit()
+}
+
+func (p Postgres) UpdateSnippetDetails(snippet types.Snippet) error {
+	_, err := p.db.Exec(`
+update snippet set title = $1, description = $2, tags = coalesce($3::varchar[], '{}'), visibility = $4 where id = $5
+`, snippet.Title, snippet.Description, pq.Array(snippet.Tags), snippet.Visibility, snippet.Id)
+	return err
+}
+
+// SetSnippetHighlight stores the highlighted contents of a revision. The snippet
+// itself is updated only if the revision is still the current one.
+func (p Postgres) SetSnippetHighlight(snippet types.SnippetId, revision int, highlight string) error {
+	_, err := p.db.Exec(`
+with r as (
+    update revision set highlighted = $1 where snippet = $2 and number = $3
+)
+update snippet set highlighted = $1 where id = $2 and revision = $3;
+`, highlight, snippet, revision)
+	return err
+}
+
+func (p Postgres) GetFiles(snippets []types.SnippetId) (map[types.SnippetId][]types.File, error) {
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		ids[i] = int64(s)
+	}
+
+	rows, err := p.db.Query(`
+select snippet, name, contents, highlighted, language from file where snippet = any($1) order by snippet, position
+`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make(map[types.SnippetId][]types.File)
+	for rows.Next() {
+		var snippet types.SnippetId
+		var f types.File
+		if err := rows.Scan(&snippet, &f.Name, &f.Contents, &f.HighlightedContents, &f.Language); err != nil {
+			return nil, err
+		}
+		files[snippet] = append(files[snippet], f)
+	}
+
+	return files, rows.Err()
+}
+
+// SetFileHighlight stores the highlighted contents of a file of a revision. The
+// file of the snippet is updated only if the revision is still the current one.
+func (p Postgres) SetFileHighlight(snippet types.SnippetId, revision int, file int, highlight string) error {
+	_, err := p.db.Exec(`
+with r as (
+    update revision_file set highlighted = $1 where snippet = $2 and number = $3 and position = $4
+)
+update file set highlighted = $1 where snippet = $2 and position = $4
+    and exists (select 1 from snippet where id = $2 and revision = $3);
+`, highlight, snippet, revision, file)
+	return err
+}
+
+func (p Postgres) GetRevisions(snippet types.SnippetId) ([]types.Revision, error) {
+	rows, err := p.db.Query(`
+select snippet, number, filename, contents, highlighted, language, createdAt from revision
+where snippet = $1 order by number
+`, snippet)
+
+	if err != nil {
+		return []types.Revision{}, err
+	}
+	defer rows.Close()
+
+	var res []types.Revision
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return []types.Revision{}, err
+		}
+		res = append(res, r)
+	}
+	if err := rows.Err(); err != nil {
+		return []types.Revision{}, err
+	}
+
+	if err := p.fillRevisionFiles(snippet, res); err != nil {
+		return []types.Revision{}, err
+	}
+	return res, nil
+}
+
+func (p Postgres) GetRevision(snippet types.SnippetId, revision int) (types.Revision, error) {
+	r, err := scanRevision(p.db.QueryRow(`
+select snippet, number, filename, contents, highlighted, language, createdAt from revision
+where snippet = $1 and number = $2
+`, snippet, revision))
+	if err != nil {
+		return types.Revision{}, err
+	}
+
+	res := []types.Revision{r}
+	if err := p.fillRevisionFiles(snippet, res); err != nil {
+		return types.Revision{}, err
+	}
+	return res[0], nil
+}
+
+// scanRevision scans a revision with its first file in Files
+func scanRevision(row rowScanner) (types.Revision, error) {
+	var r types.Revision
+	var filename string
+	if err := row.Scan(&r.Snippet, &r.Number, &filename, &r.Contents, &r.HighlightedContents, &r.Language, &r.CreatedAt); err != nil {
+		return types.Revision{}, err
+	}
+	r.Files = []types.File{{Name: filename, Contents: r.Contents, HighlightedContents: r.HighlightedContents, Language: r.Language}}
+	return r, nil
+}
+
+// fillRevisionFiles appends the files after the first one to revisions of the snippet.
+func (p Postgres) fillRevisionFiles(snippet types.SnippetId, revisions []types.Revision) error {
+	numbers := make([]int64, len(revisions))
+	byNumber := make(map[int]*types.Revision, len(revisions))
+	for i := range revisions {
+		numbers[i] = int64(revisions[i].Number)
+		byNumber[revisions[i].Number] = &revisions[i]
+	}
+
+	rows, err := p.db.Query(`
+select number, name, contents, highlighted, language from revision_file
+where snippet = $1 and number = any($2) order by number, position
+`, snippet, pq.Array(numbers))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var number int
+		var f types.File
+		if err := rows.Scan(&number, &f.Name, &f.Contents, &f.HighlightedContents, &f.Language); err != nil {
+			return err
+		}
+		r := byNumber[number]
+		r.Files = append(r.Files, f)
+	}
+	return rows.Err()
+}
+
+func (p Postgres) GetSnippetsByUser(user types.UserId, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	tail, args := snippetKeyset(page, 3)
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet where author = $1 and `+listedFor2+`
+`+tail, append([]interface{}{user, viewer}, args...)...)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetSnippetsByLanguage(language types.ProgrammingLanguage, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	tail, args := snippetKeyset(page, 3)
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet where `+hasLanguage(1)+` and `+listedFor2+`
+`+tail, append([]interface{}{language, viewer}, args...)...)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetFeed(user types.UserId, page types.Page) ([]types.Snippet, error) {
+	tail, args := snippetKeyset(page, 2)
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet
+where author in (select followee from follow where follower = $1) and `+listedForAll+`
+`+tail, append([]interface{}{user}, args...)...)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetForks(snippet types.SnippetId, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	tail, args := snippetKeyset(page, 3)
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet where forkedFrom = $1 and `+listedFor2+`
+`+tail, append([]interface{}{snippet, viewer}, args...)...)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetSnippetsByTag(tag string, viewer types.UserId, page types.Page) ([]types.Snippet, error) {
+	tail, args := snippetKeyset(page, 3)
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet where tags @> array[$1]::varchar[] and `+listedFor2+`
+`+tail, append([]interface{}{tag, viewer}, args...)...)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetPopularTags(limit int) ([]types.TagCount, error) {
+	rows, err := p.db.Query(`
+select tag, count(*) from snippet, unnest(tags) as tag
+where `+listedForAll+`
+group by tag order by count(*) desc, tag
+limit $1
+`, limit)
+
+	if err != nil {
+		return []types.TagCount{}, err
+	}
+	defer rows.Close()
+
+	var res []types.TagCount
+
+	for rows.Next() {
+		var t types.TagCount
+		if err := rows.Scan(&t.Tag, &t.Snippets); err != nil {
+			return []types.TagCount{}, err
+		}
+		res = append(res, t)
+	}
+
+	return res, rows.Err()
+}
+
+func (p Postgres) CountSnippetsByLanguage() (map[types.ProgrammingLanguage]int, error) {
+	rows, err := p.db.Query(`
+select l, count(*) from snippet
+cross join lateral (select snippet.language union select language from file where file.snippet = snippet.id) as languages (l)
+where ` + listedForAll + `
+group by l
+`)
+
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[types.ProgrammingLanguage]int)
+
+	for rows.Next() {
+		var language types.ProgrammingLanguage
+		var n int
+		if err := rows.Scan(&language, &n); err != nil {
+			return nil, err
+		}
+		counts[language] = n
+	}
+
+	return counts, rows.Err()
+}
+
+func (p Postgres) GetRankingCandidates(language types.ProgrammingLanguage, viewer types.UserId, since time.Time, limit int) ([]types.Snippet, error) {
+	window := `($1::varchar = '' or ` + hasLanguage(1) + `) and createdAt >= $3 and ` + listedFor2
+	rows, err := p.db.Query(`
+select `+candidateColumns+` from snippet where id in (
+    (select id from snippet where `+window+` order by likes - dislikes desc, id desc limit $4)
+    union
+    (select id from snippet where `+window+` order by likes desc, id desc limit $4)
+    union
+    (select id from snippet where `+window+` order by createdAt desc, id desc limit $4)
+)
+`, language, viewer, since.UTC(), limit)
+
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) GetListedSnippets(snippets []types.SnippetId, viewer types.UserId) ([]types.Snippet, error) {
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		ids[i] = int64(s)
+	}
+
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet
+where id in (select unnest($1::int[])) and `+listedFor2+`
+order by array_position($1::int[], id)
+`, pq.Array(ids), viewer)
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+// headlineOptions make ts_headline mark up matches the way search.RenderFragments expects
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, FragmentDelimiter=%s, MaxFragments=%d, MaxWords=%d, MinWords=%d",
+	search.MatchStart, search.MatchEnd, search.FragmentDelimiter, search.MaxFragments, search.FragmentWords, search.FragmentWords/2)
+
+// SearchSnippets ranks snippets by the score of the snippet itself plus half
+// the best score of its comments.
+func (p Postgres) SearchSnippets(query types.SearchQuery, viewer types.UserId) ([]types.SearchResult, error) {
+	rows, err := p.db.Query(`
+select `+snippetColumns+`, ts_rank(search, query) + coalesce(c.rank, 0) / 2 as score,
+    ts_headline('simple', title || E'\n' || description || E'\n' || `+allCode+`, query, $6), coalesce(c.headline, '')
+from snippet
+cross join websearch_to_tsquery('simple', $1) as query
+left join lateral (
+    select max(ts_rank(comment.search, query)) as rank,
+        ts_headline('simple', string_agg(comment.contents, E'\n'), query, $6) as headline
+    from comment where comment.snippet = snippet.id and comment.search @@ query
+) c on c.rank is not null
+where (search @@ query or c.rank is not null) and `+listedFor2+`
+    and ($3::varchar = '' or `+hasLanguage(3)+`) and ($4::int = 0 or author = $4)
+order by score desc, id desc
+limit $5
+`, query.Text, viewer, query.Language, query.Author, query.Limit, headlineOptions)
+	if err != nil {
+		return []types.SearchResult{}, err
+	}
+	defer rows.Close()
+
+	var res []types.SearchResult
+	for rows.Next() {
+		var r types.SearchResult
+		var snippetHeadline, commentHeadline string
+		dest := append(snippetFields(&r.Snippet), &r.Rank, &snippetHeadline, &commentHeadline)
+		if err := rows.Scan(dest...); err != nil {
+			return []types.SearchResult{}, err
+		}
+		r.Fragments = search.RenderFragments(snippetHeadline, commentHeadline)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// codeSearchBatch is the number of candidates SearchCode fetches at a time
+const codeSearchBatch = 100
+
+// maxCodeSearchCandidates bounds the candidates a single code search looks through,
+// patterns without literals to look for are matched against every snippet otherwise
+const maxCodeSearchCandidates = 10000
+
+// SearchCode picks candidates with the identifier and trigram indexes and
+// confirms matches with Go regular expressions, which differ from the ones
+// of Postgres, fetching candidates in batches until there are enough matches.
+func (p Postgres) SearchCode(query types.CodeSearchQuery, viewer types.UserId) ([]types.Snippet, error) {
+	args := []interface{}{query.Language, viewer, query.Author}
+	var condition string
+	var matches func(contents string) bool
+	switch query.Mode {
+	case types.CodeSearchIdentifier:
+		args = append(args, search.NormalizeIdentifier(query.Pattern))
+		condition = `identifiers @> array[$4]::varchar[]`
+		matches = func(contents string) bool {
+			return len(search.IdentifierMatches(contents, query.Pattern)) > 0
+		}
+	case types.CodeSearchRegexp:
+		re, err := regexp.Compile(query.Pattern)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		f, err := search.RegexpPrefilter(query.Pattern)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		condition = prefilterCondition(f, &args)
+		matches = func(contents string) bool {
+			return len(search.RegexpMatches(contents, re)) > 0
+		}
+	default:
+		return []types.Snippet{}, types.ErrInvalidCodeSearchMode
+	}
+
+	res := []types.Snippet{}
+	page := types.Page{Limit: codeSearchBatch, Sort: types.SortNewest}
+	for scanned := 0; scanned < maxCodeSearchCandidates; scanned += page.Limit {
+		tail, keysetArgs := snippetKeyset(page, len(args)+1)
+		rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet
+where `+condition+` and `+listedFor2+`
+    and ($1::varchar = '' or `+hasLanguage(1)+`) and ($3::int = 0 or author = $3)
+`+tail, append(args, keysetArgs...)...)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		candidates, err := scanSnippets(rows)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+		ids := make([]types.SnippetId, len(candidates))
+		for i, s := range candidates {
+			ids[i] = s.Id
+		}
+		files, err := p.GetFiles(ids)
+		if err != nil {
+			return []types.Snippet{}, err
+		}
+
+		for _, s := range candidates {
+			if anyFile(append([]types.File{s.MainFile()}, files[s.Id]...), matches) {
+				res = append(res, s)
+				if len(res) == query.Limit {
+					return res, nil
+				}
+			}
+		}
+		if len(candidates) < page.Limit {
+			break
+		}
+		page.After = page.Sort.SnippetCursor(candidates[len(candidates)-1])
+	}
+	return res, nil
+}
+
+// anyFile reports whether contents of any of files satisfy match.
+func anyFile(files []types.File, match func(contents string) bool) bool {
+	for _, f := range files {
+		if match(f.Contents) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefilterCondition turns a prefilter into conditions on code the trigram
+// index answers, appending the LIKE patterns to args.
+func prefilterCondition(f search.Prefilter, args *[]interface{}) string {
+	var conditions []string
+	switch f.Op {
+	case search.PrefilterAnd:
+		for _, l := range f.Literals {
+			*args = append(*args, "%"+likeEscaper.Replace(l)+"%")
+			conditions = append(conditions, fmt.Sprintf(allCode+` ilike $%d`, len(*args)))
+		}
+		for _, s := range f.Subs {
+			conditions = append(conditions, prefilterCondition(s, args))
+		}
+		return "(" + strings.Join(conditions, " and ") + ")"
+	case search.PrefilterOr:
+		for _, s := range f.Subs {
+			conditions = append(conditions, prefilterCondition(s, args))
+		}
+		return "(" + strings.Join(conditions, " or ") + ")"
+	}
+	return "true"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (p Postgres) GetSnippet(snippet types.SnippetId) (types.Snippet, error) {
+	row := p.db.QueryRow(`
+select `+snippetColumns+` from snippet where id = $1
+`, snippet)
+
+	s, err := scanSnippet(row)
+	if err != sql.ErrNoRows {
+		return s, err
+	}
+
+	var expired bool
+	if err := p.db.QueryRow(`
+select exists(select 1 from expired where snippet = $1)
+`, snippet).Scan(&expired); err != nil {
+		return types.Snippet{}, err
+	}
+	if expired {
+		return types.Snippet{}, types.ErrSnippetExpired
+	}
+	return types.Snippet{}, sql.ErrNoRows
+}
+
+// BurnSnippet expires a burn-after-read snippet right away. It reports
+// whether the snippet was still alive, so only the first reader gets it.
+func (p Postgres) BurnSnippet(snippet types.SnippetId) (bool, error) {
+	res, err := p.db.Exec(`
+update snippet set expiresAt = now() where id = $1 and burnOnRead and `+notExpired+`
+`, snippet)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteExpiredSnippets deletes expired snippets along with their comments,
+// votes and revisions, and returns the number of deleted snippets.
+func (p Postgres) DeleteExpiredSnippets() (int, error) {
+	res, err := p.db.Exec(`
+with gone as (
+    delete from snippet where expiresAt <= now() returning id
+)
+insert into expired (snippet, expiredAt) select id, now() from gone
+`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (p Postgres) DeleteSnippet(snippet types.SnippetId) error {
+	_, err := p.db.Exec(`
+delete from snippet where id = $1
+`, snippet)
+	return err
+}
+
+func (p Postgres) Vote(user types.UserId, snippet types.SnippetId, vote int) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the snippet row so that concurrent votes on it are applied one by one
+	if _, err := tx.Exec(`
+select id from snippet where id = $1 for update
+`, snippet); err != nil {
+		return err
+	}
+
+	var old int
+	err = tx.QueryRow(`
+select vote from vote where snippet = $1 and "user" = $2
+`, snippet, user).Scan(&old)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+insert into vote (snippet, "user", vote) values ($1, $2, $3)
+on conflict on constraint vote_pkey do update
+set vote = $3;
+`, snippet, user, vote); err != nil {
+		return err
+	}
+
+	delta := ratingDelta(old, vote)
+	if _, err := tx.Exec(`
+update snippet set likes = likes + $1, dislikes = dislikes + $2 where id = $3
+`, delta.Likes, delta.Dislikes, snippet); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p Postgres) GetVote(user types.UserId, snippet types.SnippetId) (int, error) {
+	row := p.db.QueryRow(`
+select vote from vote where snippet = $1 and "user" = $2;
+`, snippet, user)
+	var vote int
+	err := row.Scan(&vote)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return vote, nil
+}
+
+func (p Postgres) GetVotes(user types.UserId, snippets []types.SnippetId) (map[types.SnippetId]int, error) {
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		ids[i] = int64(s)
+	}
+
+	rows, err := p.db.Query(`
+select snippet, vote from vote where "user" = $1 and snippet = any($2)
+`, user, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make(map[types.SnippetId]int)
+	for rows.Next() {
+		var snippet types.SnippetId
+		var vote int
+		if err := rows.Scan(&snippet, &vote); err != nil {
+			return nil, err
+		}
+		votes[snippet] = vote
+	}
+
+	return votes, rows.Err()
+}
+
+func (p Postgres) AddReaction(user types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	_, err := p.db.Exec(`
+insert into reaction (snippet, "user", reaction) values ($1, $2, $3)
+on conflict do nothing
+`, snippet, user, reaction)
+	return err
+}
+
+func (p Postgres) RemoveReaction(user types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	_, err := p.db.Exec(`
+delete from reaction where snippet = $1 and "user" = $2 and reaction = $3
+`, snippet, user, reaction)
+	return err
+}
+
+func (p Postgres) GetReactions(user types.UserId, snippets []types.SnippetId) (map[types.SnippetId]types.Reactions, error) {
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		ids[i] = int64(s)
+	}
+
+	rows, err := p.db.Query(`
+select snippet, reaction, count(*), bool_or("user" = $1) from reaction
+where snippet = any($2)
+group by snippet, reaction
+`, user, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	sums, err := scanReactions(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[types.SnippetId]types.Reactions, len(sums))
+	for id, r := range sums {
+		res[types.SnippetId(id)] = r
+	}
+	return res, nil
+}
+
+// scanReactions sums up rows of reactions grouped by the id of what they react to:
+// the id, the reaction, its count and whether the viewer has left it.
+func scanReactions(rows *sql.Rows) (map[uint]types.Reactions, error) {
+	defer rows.Close()
+
+	res := make(map[uint]types.Reactions)
+	for rows.Next() {
+		var id uint
+		var reaction types.Reaction
+		var count int
+		var mine bool
+		if err := rows.Scan(&id, &reaction, &count, &mine); err != nil {
+			return nil, err
+		}
+
+		r, ok := res[id]
+		if !ok {
+			r.Counts = make(map[types.Reaction]int)
+		}
+		r.Counts[reaction] = count
+		if mine {
+			r.CurrentUser = append(r.CurrentUser, reaction)
+		}
+		res[id] = r
+	}
+
+	return res, rows.Err()
+}
+
+// ReconcileRatings recomputes like/dislike counters of every snippet from
+// the vote table and returns the number of snippets that were out of sync.
+func (p Postgres) ReconcileRatings() (int, error) {
+	res, err := p.db.Exec(`
+with counts as (
+    select s.id,
+           count(v.vote) filter (where v.vote = 1)  as likes,
+           count(v.vote) filter (where v.vote = -1) as dislikes
+    from snippet s left join vote v on v.snippet = s.id
+    group by s.id
+)
+update snippet s set likes = c.likes, dislikes = c.dislikes
+from counts c
+where c.id = s.id and (s.likes <> c.likes or s.dislikes <> c.dislikes)
+`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// NormalizeLanguages replaces languages of snippets, their files and revisions
+// with identifiers of the registry and returns the number of snippets changed.
+func (p Postgres) NormalizeLanguages() (int, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+select language from snippet union select language from file
+union select language from revision union select language from revision_file
+`)
+	if err != nil {
+		return 0, err
+	}
+	var legacy, canonical []string
+	for rows.Next() {
+		var language types.ProgrammingLanguage
+		if err := rows.Scan(&language); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if l := canonicalLanguage(language); l != language {
+			legacy = append(legacy, string(language))
+			canonical = append(canonical, string(l))
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(legacy) == 0 {
+		return 0, nil
+	}
+
+	var n int
+	err = tx.QueryRow(`
+with m as (
+    select * from unnest($1::varchar[], $2::varchar[]) as m (legacy, canonical)
+), s as (
+    update snippet set language = m.canonical from m where snippet.language = m.legacy returning snippet.id
+), f as (
+    update file set language = m.canonical from m where file.language = m.legacy returning file.snippet
+), r as (
+    update revision set language = m.canonical from m where revision.language = m.legacy returning revision.snippet
+), rf as (
+    update revision_file set language = m.canonical from m where revision_file.language = m.legacy returning revision_file.snippet
+)
+select count(*) from (select id from s union select snippet from f union select snippet from r union select snippet from rf) as changed
+`, pq.Array(legacy), pq.Array(canonical)).Scan(&n)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func (p Postgres) AddComment(comment types.Comment) (types.CommentId, error) {
+	var id int
+	err := p.db.QueryRow(`
+insert into comment (parent, depth, contents, rendered, snippet, revision, file, lineFrom, lineTo, author, createdAt)
+values (nullif($1, 0), $2, $3, $4, $5, $6, $7, nullif($8, 0), nullif($9, 0), $10, now()) returning (id);
+`, comment.ParentId, comment.Depth, comment.Contents, comment.Rendered, comment.Snippet, comment.Revision, comment.File, comment.Lines.From, comment.Lines.To, comment.Author).Scan(&id)
+	if err != nil {
+		return 0, err
 	}
 
-	return Postgres{db}, nil
+	return types.CommentId(id), nil
 }
 
-func (p Postgres) Close() error {
-	return p.db.Close()
+func (p Postgres) GetComment(comment types.CommentId) (types.Comment, error) {
+	row := p.db.QueryRow(`
+select `+commentColumns+` from comment where id = $1
+`, comment)
+
+	return scanComment(row)
 }
 
-func (p Postgres) AddSnippet(snippet types.Snippet) (types.SnippetId, error) {
-	var id int
-	err := p.db.QueryRow(`
-	insert into snippet (contents, language, author, createdAt) values
-	($1, $2, $3, now()) returning (id);
-`, snippet.Contents, snippet.Language, snippet.Author).Scan(&id)
+func (p Postgres) GetComments(snippet types.SnippetId, page types.Page) ([]types.Comment, error) {
+	tail, args := commentKeyset(page, 2)
+	rows, err := p.db.Query(`
+select `+commentColumns+` from comment where snippet = $1 and parent is null
+`+tail, append([]interface{}{snippet}, args...)...)
+
 	if err != nil {
-		return types.SnippetId(0), err
+		return []types.Comment{}, err
 	}
 
-	return types.SnippetId(id), nil
+	return scanComments(rows)
 }
 
-func (p Postgres) SetSnippetHighlight(snippet types.SnippetId, highlight string) error {
-	_, err := p.db.Exec(`
-	update snippet set highlighted = $1 where id = $2;
-`, highlight, snippet)
-	return err
+func (p Postgres) GetLineComments(snippet types.SnippetId) ([]types.Comment, error) {
+	rows, err := p.db.Query(`
+select `+commentColumns+` from comment where snippet = $1 and parent is null and lineFrom is not null
+order by createdAt, id
+`, snippet)
+
+	if err != nil {
+		return []types.Comment{}, err
+	}
+
+	return scanComments(rows)
 }
 
-func (p Postgres) GetSnippetsByUser(user types.UserId) ([]types.Snippet, error) {
+func (p Postgres) GetReplies(comments []types.CommentId) ([]types.Comment, error) {
+	ids := make([]int64, len(comments))
+	for i, c := range comments {
+		ids[i] = int64(c)
+	}
+
 	rows, err := p.db.Query(`
-select id, contents, highlighted, language, author, likes, dislikes, createdAt from snippet where author = $1
-`, user)
+with recursive thread as (
+    select id from comment where parent = any($1)
+    union all
+    select comment.id from comment join thread on comment.parent = thread.id
+)
+select `+commentColumns+` from comment where id in (select id from thread)
+order by createdAt, id
+`, pq.Array(ids))
 
 	if err != nil {
-		return []types.Snippet{}, err
+		return []types.Comment{}, err
 	}
 
-	var res []types.Snippet
+	return scanComments(rows)
+}
 
-	for rows.Next() {
-		var s types.Snippet
-		err = rows.Scan(&s.Id, &s.Contents, &s.HighlightedContents, &s.Language, &s.Author, &s.Rating.Likes, &s.Rating.Dislikes, &s.CreatedAt)
-		if err != nil {
-			return []types.Snippet{}, err
-		}
-		res = append(res, s)
+func (p Postgres) DeleteComment(comment types.CommentId) error {
+	_, err := p.db.Exec(`
+delete from comment where id = $1
+`, comment)
+	return err
+}
+
+func (p Postgres) UpdateComment(comment types.CommentId, contents, rendered string) (types.Comment, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return types.Comment{}, err
 	}
+	defer tx.Rollback()
 
-	return res, nil
+	if _, err := tx.Exec(`
+insert into comment_revision (comment, contents, createdAt)
+select id, contents, coalesce(editedAt, createdAt) from comment where id = $1
+`, comment); err != nil {
+		return types.Comment{}, err
+	}
+
+	c, err := scanComment(tx.QueryRow(`
+update comment set contents = $1, rendered = $3, editedAt = now() where id = $2 returning `+commentColumns+`
+`, contents, comment, rendered))
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	return c, tx.Commit()
 }
 
-func (p Postgres) GetSnippetsByLanguage(language types.ProgrammingLanguage) ([]types.Snippet, error) {
+func (p Postgres) GetCommentHistory(comment types.CommentId) ([]types.CommentRevision, error) {
 	rows, err := p.db.Query(`
-select id, contents, highlighted, language, author, likes, dislikes, createdAt from snippet where language = $1
-`, language)
+select comment, contents, createdAt from comment_revision where comment = $1 order by createdAt
+`, comment)
 
 	if err != nil {
-		return []types.Snippet{}, err
+		return []types.CommentRevision{}, err
 	}
+	defer rows.Close()
 
-	var res []types.Snippet
+	var res []types.CommentRevision
 
 	for rows.Next() {
-		var s types.Snippet
-		err = rows.Scan(&s.Id, &s.Contents, &s.HighlightedContents, &s.Language, &s.Author, &s.Rating.Likes, &s.Rating.Dislikes, &s.CreatedAt)
-		if err != nil {
-			return []types.Snippet{}, err
+		var r types.CommentRevision
+		if err := rows.Scan(&r.Comment, &r.Contents, &r.CreatedAt); err != nil {
+			return []types.CommentRevision{}, err
 		}
-		res = append(res, s)
+		res = append(res, r)
 	}
 
-	return res, nil
+	return res, rows.Err()
 }
 
-func (p Postgres) GetSnippet(snippet types.SnippetId) (types.Snippet, error) {
-	row := p.db.QueryRow(`
-select id, contents, highlighted, language, author, likes, dislikes, createdAt from snippet where id = $1
-`, snippet)
-
-	var s types.Snippet
-	err := row.Scan(&s.Id, &s.Contents, &s.HighlightedContents, &s.Language, &s.Author, &s.Rating.Likes, &s.Rating.Dislikes, &s.CreatedAt)
+func (p Postgres) VoteComment(user types.UserId, comment types.CommentId, vote int) error {
+	tx, err := p.db.Begin()
 	if err != nil {
-		return types.Snippet{}, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return s, nil
-}
+	// Lock the comment row so that concurrent votes on it are applied one by one
+	if _, err := tx.Exec(`
+select id from comment where id = $1 for update
+`, comment); err != nil {
+		return err
+	}
 
-func (p Postgres) DeleteSnippet(snippet types.SnippetId) error {
-	_, err := p.db.Exec(`
-delete from snippet where id = $1
-`, snippet)
-	return err
-}
+	var old int
+	err = tx.QueryRow(`
+select vote from comment_vote where comment = $1 and "user" = $2
+`, comment, user).Scan(&old)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
 
-func (p Postgres) Vote(user types.UserId, snippet types.SnippetId, vote int) error {
-	_, err := p.db.Exec(`
-insert into vote (snippet, "user", vote) values ($1, $2, $3)
-on conflict on constraint vote_pkey do update
+	if _, err := tx.Exec(`
+insert into comment_vote (comment, "user", vote) values ($1, $2, $3)
+on conflict on constraint comment_vote_pkey do update
 set vote = $3;
-`, snippet, user, vote)
-	return err
-}
-
-func (p Postgres) GetVote(user types.UserId, snippet types.SnippetId) (int, error) {
-	row := p.db.QueryRow(`
-select vote from vote where snippet = $1 and user = $2;
-`, snippet, user)
-	var vote int
-	err := row.Scan(&vote)
-	if err == sql.ErrNoRows {
-		return 0, nil
-	} else if err != nil {
-		return 0, err
+`, comment, user, vote); err != nil {
+		return err
 	}
-	return vote, nil
-}
 
-func (p Postgres) AddComment(comment types.Comment) (types.CommentId, error) {
-	var id int
-	err := p.db.QueryRow(`
-insert into comment (contents, snippet, author, createdAt) values ($1, $2, $3, now()) returning (id);
-`, comment.Contents, comment.Snippet, comment.Author).Scan(&id)
-	if err != nil {
-		return 0, err
+	delta := ratingDelta(old, vote)
+	if _, err := tx.Exec(`
+update comment set likes = likes + $1, dislikes = dislikes + $2 where id = $3
+`, delta.Likes, delta.Dislikes, comment); err != nil {
+		return err
 	}
 
-	return types.CommentId(id), nil
+	return tx.Commit()
 }
 
-func (p Postgres) GetComment(comment types.CommentId) (types.Comment, error) {
-	row := p.db.QueryRow(`
-select id, contents, snippet, author, createdAt from comment where id = $1
-`, comment)
+func (p Postgres) GetCommentVotes(user types.UserId, comments []types.CommentId) (map[types.CommentId]int, error) {
+	ids := make([]int64, len(comments))
+	for i, c := range comments {
+		ids[i] = int64(c)
+	}
 
-	var c types.Comment
-	err := row.Scan(&c.Id, &c.Contents, &c.Snippet, &c.Author, &c.CreatedAt)
+	rows, err := p.db.Query(`
+select comment, vote from comment_vote where "user" = $1 and comment = any($2)
+`, user, pq.Array(ids))
 	if err != nil {
-		return types.Comment{}, err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return c, nil
+	votes := make(map[types.CommentId]int)
+	for rows.Next() {
+		var comment types.CommentId
+		var vote int
+		if err := rows.Scan(&comment, &vote); err != nil {
+			return nil, err
+		}
+		votes[comment] = vote
+	}
+
+	return votes, rows.Err()
 }
 
-func (p Postgres) GetComments(snippet types.SnippetId) ([]types.Comment, error) {
-	rows, err := p.db.Query(`
-select id, contents, snippet, author, createdAt from comment where snippet = $1
-`, snippet)
+func (p Postgres) AddCommentReaction(user types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	_, err := p.db.Exec(`
+insert into comment_reaction (comment, "user", reaction) values ($1, $2, $3)
+on conflict do nothing
+`, comment, user, reaction)
+	return err
+}
 
-	if err != nil {
-		return []types.Comment{}, err
+func (p Postgres) RemoveCommentReaction(user types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	_, err := p.db.Exec(`
+delete from comment_reaction where comment = $1 and "user" = $2 and reaction = $3
+`, comment, user, reaction)
+	return err
+}
+
+func (p Postgres) GetCommentReactions(user types.UserId, comments []types.CommentId) (map[types.CommentId]types.Reactions, error) {
+	ids := make([]int64, len(comments))
+	for i, c := range comments {
+		ids[i] = int64(c)
 	}
 
-	var res []types.Comment
+	rows, err := p.db.Query(`
+select comment, reaction, count(*), bool_or("user" = $1) from comment_reaction
+where comment = any($2)
+group by comment, reaction
+`, user, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		var c types.Comment
-		err = rows.Scan(&c.Id, &c.Contents, &c.Snippet, &c.Author, &c.CreatedAt)
-		if err != nil {
-			return []types.Comment{}, err
-		}
-		res = append(res, c)
+	sums, err := scanReactions(rows)
+	if err != nil {
+		return nil, err
 	}
 
+	res := make(map[types.CommentId]types.Reactions, len(sums))
+	for id, r := range sums {
+		res[types.CommentId(id)] = r
+	}
 	return res, nil
 }
 
-func (p Postgres) DeleteComment(comment types.CommentId) error {
+func (p Postgres) TombstoneComment(comment types.CommentId) error {
 	_, err := p.db.Exec(`
-delete from comment where id = $1
+update comment set deleted = true, contents = '', rendered = '' where id = $1
 `, comment)
 	return err
 }
@@ -227,3 +1284,221 @@ select id, username, password from "user" where username = $1
 	}
 	return a, nil
 }
+
+func (p Postgres) Follow(follower types.UserId, followee types.UserId) error {
+	_, err := p.db.Exec(`
+insert into follow (follower, followee) values ($1, $2)
+on conflict on constraint follow_pkey do nothing;
+`, follower, followee)
+	return err
+}
+
+func (p Postgres) Unfollow(follower types.UserId, followee types.UserId) error {
+	_, err := p.db.Exec(`
+delete from follow where follower = $1 and followee = $2
+`, follower, followee)
+	return err
+}
+
+func (p Postgres) IsFollowing(follower types.UserId, followee types.UserId) (bool, error) {
+	var following bool
+	err := p.db.QueryRow(`
+select exists(select 1 from follow where follower = $1 and followee = $2)
+`, follower, followee).Scan(&following)
+	if err != nil {
+		return false, err
+	}
+	return following, nil
+}
+
+func (p Postgres) GetFollowers(user types.UserId) ([]types.User, error) {
+	rows, err := p.db.Query(`
+select u.id, u.username from follow f join "user" u on u.id = f.follower where f.followee = $1
+`, user)
+
+	if err != nil {
+		return []types.User{}, err
+	}
+
+	return scanUsers(rows)
+}
+
+func (p Postgres) GetFollowing(user types.UserId) ([]types.User, error) {
+	rows, err := p.db.Query(`
+select u.id, u.username from follow f join "user" u on u.id = f.followee where f.follower = $1
+`, user)
+
+	if err != nil {
+		return []types.User{}, err
+	}
+
+	return scanUsers(rows)
+}
+
+func (p Postgres) AddBookmark(user types.UserId, snippet types.SnippetId) error {
+	_, err := p.db.Exec(`
+insert into bookmark ("user", snippet, createdAt) values ($1, $2, now())
+on conflict on constraint bookmark_pkey do nothing
+`, user, snippet)
+	return err
+}
+
+func (p Postgres) RemoveBookmark(user types.UserId, snippet types.SnippetId) error {
+	_, err := p.db.Exec(`
+delete from bookmark where "user" = $1 and snippet = $2
+`, user, snippet)
+	return err
+}
+
+func (p Postgres) GetBookmarks(user types.UserId) ([]types.Snippet, error) {
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet
+join (select snippet as bookmarked, createdAt as bookmarkedAt from bookmark where "user" = $1) b on b.bookmarked = snippet.id
+where (visibility <> 'private' and not burnOnRead or author = $1) and `+notExpired+`
+order by bookmarkedAt desc
+`, user)
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func scanCollection(row rowScanner) (types.Collection, error) {
+	var c types.Collection
+	err := row.Scan(&c.Id, &c.Owner, &c.Name, &c.Public, &c.CreatedAt)
+	if err != nil {
+		return types.Collection{}, err
+	}
+	return c, nil
+}
+
+func (p Postgres) AddCollection(collection types.Collection) (types.CollectionId, error) {
+	var id types.CollectionId
+	err := p.db.QueryRow(`
+insert into collection (owner, name, public, createdAt) values ($1, $2, $3, now()) returning id
+`, collection.Owner, collection.Name, collection.Public).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (p Postgres) GetCollection(collection types.CollectionId) (types.Collection, error) {
+	return scanCollection(p.db.QueryRow(`
+select `+collectionColumns+` from collection where id = $1
+`, collection))
+}
+
+func (p Postgres) GetCollections(owner types.UserId) ([]types.Collection, error) {
+	rows, err := p.db.Query(`
+select `+collectionColumns+` from collection where owner = $1 order by createdAt, id
+`, owner)
+	if err != nil {
+		return []types.Collection{}, err
+	}
+	defer rows.Close()
+
+	var res []types.Collection
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return []types.Collection{}, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (p Postgres) UpdateCollection(collection types.Collection) error {
+	_, err := p.db.Exec(`
+update collection set name = $1, public = $2 where id = $3
+`, collection.Name, collection.Public, collection.Id)
+	return err
+}
+
+func (p Postgres) DeleteCollection(collection types.CollectionId) error {
+	_, err := p.db.Exec(`
+delete from collection where id = $1
+`, collection)
+	return err
+}
+
+func (p Postgres) AddToCollection(collection types.CollectionId, snippet types.SnippetId) error {
+	_, err := p.db.Exec(`
+insert into collection_snippet (collection, snippet, position)
+select $1, $2, coalesce(max(position), 0) + 1 from collection_snippet where collection = $1
+on conflict on constraint collection_snippet_pkey do nothing
+`, collection, snippet)
+	return err
+}
+
+func (p Postgres) RemoveFromCollection(collection types.CollectionId, snippet types.SnippetId) error {
+	_, err := p.db.Exec(`
+delete from collection_snippet where collection = $1 and snippet = $2
+`, collection, snippet)
+	return err
+}
+
+func (p Postgres) GetCollectionItems(collection types.CollectionId) ([]types.SnippetId, error) {
+	rows, err := p.db.Query(`
+select snippet from collection_snippet where collection = $1 order by position, snippet
+`, collection)
+	if err != nil {
+		return []types.SnippetId{}, err
+	}
+	defer rows.Close()
+
+	var res []types.SnippetId
+	for rows.Next() {
+		var id types.SnippetId
+		if err := rows.Scan(&id); err != nil {
+			return []types.SnippetId{}, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
+
+func (p Postgres) GetCollectionSnippets(collection types.CollectionId, viewer types.UserId) ([]types.Snippet, error) {
+	rows, err := p.db.Query(`
+select `+snippetColumns+` from snippet join collection_snippet c on c.snippet = snippet.id
+where c.collection = $1 and `+visibleTo2+`
+order by c.position, c.snippet
+`, collection, viewer)
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	return scanSnippets(rows)
+}
+
+func (p Postgres) ReorderCollection(collection types.CollectionId, snippets []types.SnippetId) error {
+	ids := make([]int64, len(snippets))
+	for i, s := range snippets {
+		ids[i] = int64(s)
+	}
+
+	_, err := p.db.Exec(`
+update collection_snippet c set position = o.position
+from unnest($2::int[]) with ordinality as o (snippet, position)
+where c.collection = $1 and c.snippet = o.snippet
+`, collection, pq.Array(ids))
+	return err
+}
+
+func scanUsers(rows *sql.Rows) ([]types.User, error) {
+	defer rows.Close()
+
+	var res []types.User
+
+	for rows.Next() {
+		var u types.User
+		if err := rows.Scan(&u.Id, &u.Username); err != nil {
+			return []types.User{}, err
+		}
+		res = append(res, u)
+	}
+
+	return res, rows.Err()
+}
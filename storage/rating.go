@@ -0,0 +1,22 @@
+package storage
+
+import "github.com/mp-hl-2021/splinter/types"
+
+// ratingDelta returns how like/dislike counters of a snippet or a comment change
+// when a user's vote changes from old to new.
+func ratingDelta(old, new int) types.Rating {
+	var d types.Rating
+	switch old {
+	case 1:
+		d.Likes--
+	case -1:
+		d.Dislikes--
+	}
+	switch new {
+	case 1:
+		d.Likes++
+	case -1:
+		d.Dislikes++
+	}
+	return d
+}
@@ -0,0 +1,877 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/mp-hl-2021/splinter/auth"
+	"github.com/mp-hl-2021/splinter/types"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testStorage is everything the shared tests need from a storage.
+type testStorage interface {
+	auth.UserStorage
+	types.SnippetStorage
+	types.SearchStorage
+	types.FollowStorage
+	types.CollectionStorage
+	ReconcileRatings() (int, error)
+	NormalizeLanguages() (int, error)
+}
+
+// forEachStorage runs test against the memory storage and, if SPLINTER_TEST_POSTGRES
+// holds a connection string of a database initialized with initdb.sql, against Postgres.
+// The database isn't cleaned up, tests create users and names of their own.
+func forEachStorage(t *testing.T, test func(t *testing.T, s testStorage)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		connStr := os.Getenv("SPLINTER_TEST_POSTGRES")
+		if connStr == "" {
+			t.Skip("SPLINTER_TEST_POSTGRES is not set")
+		}
+		p, err := NewPostgres(connStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		test(t, p)
+	})
+}
+
+var uniqueCounter int64
+
+// unique returns a word no other test run has used.
+func unique(prefix string) string {
+	return fmt.Sprintf("%s%dx%d", prefix, time.Now().UnixNano(), atomic.AddInt64(&uniqueCounter, 1))
+}
+
+func newUser(t *testing.T, s testStorage) types.UserId {
+	t.Helper()
+	a, err := s.CreateAccount(auth.Credentials{Username: unique("user"), Password: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.UserId(a.Id)
+}
+
+func addSnippet(t *testing.T, s testStorage, snippet types.Snippet) types.SnippetId {
+	t.Helper()
+	if snippet.Visibility == "" {
+		snippet.Visibility = types.VisibilityPublic
+	}
+	if snippet.Language == "" {
+		snippet.Language = "go"
+	}
+	id, err := s.AddSnippet(snippet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func ids(snippets []types.Snippet) map[types.SnippetId]bool {
+	res := make(map[types.SnippetId]bool, len(snippets))
+	for _, s := range snippets {
+		res[s.Id] = true
+	}
+	return res
+}
+
+var firstPage = types.Page{Limit: types.MaxPageLimit, Sort: types.SortNewest}
+
+func TestBurnAfterReadSnippetsAreNotListed(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author, viewer := newUser(t, s), newUser(t, s)
+		word, tag, identifier := unique("word"), unique("tag"), unique("ident")
+		language := types.ProgrammingLanguage(unique("lang"))
+		parent := addSnippet(t, s, types.Snippet{Author: author, Contents: "package main"})
+
+		snippet := types.Snippet{
+			Title:      word,
+			Tags:       []string{tag},
+			Contents:   "var " + identifier + " = 1",
+			Language:   language,
+			Author:     author,
+			ForkedFrom: parent,
+		}
+		listed := addSnippet(t, s, snippet)
+		snippet.BurnAfterRead = true
+		burning := addSnippet(t, s, snippet)
+
+		if err := s.Follow(viewer, author); err != nil {
+			t.Fatal(err)
+		}
+		collection, err := s.AddCollection(types.Collection{Owner: viewer, Name: word, Public: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []types.SnippetId{listed, burning} {
+			if err := s.AddBookmark(viewer, id); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.AddToCollection(collection, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		listings := map[string]func(viewer types.UserId) ([]types.Snippet, error){
+			"user": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetSnippetsByUser(author, viewer, firstPage)
+			},
+			"language": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetSnippetsByLanguage(language, viewer, firstPage)
+			},
+			"forks": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetForks(parent, viewer, firstPage)
+			},
+			"tag": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetSnippetsByTag(tag, viewer, firstPage)
+			},
+			"ranking": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetRankingCandidates(language, viewer, time.Now().Add(-time.Hour), types.MaxPageLimit)
+			},
+			"ids": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetListedSnippets([]types.SnippetId{burning, listed}, viewer)
+			},
+			"search": func(viewer types.UserId) ([]types.Snippet, error) {
+				results, err := s.SearchSnippets(types.SearchQuery{Text: word, Limit: types.MaxPageLimit}, viewer)
+				var res []types.Snippet
+				for _, r := range results {
+					res = append(res, r.Snippet)
+				}
+				return res, err
+			},
+			"code search": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.SearchCode(types.CodeSearchQuery{Mode: types.CodeSearchIdentifier, Pattern: identifier, Limit: types.MaxPageLimit}, viewer)
+			},
+			"collection": func(viewer types.UserId) ([]types.Snippet, error) {
+				return s.GetCollectionSnippets(collection, viewer)
+			},
+		}
+		for name, list := range listings {
+			got, err := list(viewer)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if found := ids(got); !found[listed] || found[burning] {
+				t.Errorf("%s: others see %v, want %d but not %d", name, found, listed, burning)
+			}
+			got, err = list(author)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if found := ids(got); !found[listed] || !found[burning] {
+				t.Errorf("%s: the author sees %v, want both %d and %d", name, found, listed, burning)
+			}
+		}
+
+		feed, err := s.GetFeed(viewer, firstPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found := ids(feed); !found[listed] || found[burning] {
+			t.Errorf("feed has %v, want %d but not %d", found, listed, burning)
+		}
+		bookmarks, err := s.GetBookmarks(viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found := ids(bookmarks); !found[listed] || found[burning] {
+			t.Errorf("bookmarks have %v, want %d but not %d", found, listed, burning)
+		}
+
+		counts, err := s.CountSnippetsByLanguage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts[language] != 1 {
+			t.Errorf("%d snippets counted, want 1", counts[language])
+		}
+		tags, err := s.GetPopularTags(types.MaxPageLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tags {
+			if c.Tag == tag && c.Snippets != 1 {
+				t.Errorf("%d snippets tagged, want 1", c.Snippets)
+			}
+		}
+	})
+}
+
+// votingScript returns the votes a user casts one after another, likes,
+// dislikes, changes and retractions, and the vote that stays.
+func votingScript(r *rand.Rand) ([]int, int) {
+	votes := make([]int, 1+r.Intn(8))
+	for i := range votes {
+		votes[i] = r.Intn(3) - 1
+	}
+	return votes, votes[len(votes)-1]
+}
+
+func TestConcurrentVotesKeepRatingsConsistent(t *testing.T) {
+	const users, snippets = 40, 3
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		var snippetIds []types.SnippetId
+		for i := 0; i < snippets; i++ {
+			snippetIds = append(snippetIds, addSnippet(t, s, types.Snippet{Author: author, Contents: "x"}))
+		}
+		voters := make([]types.UserId, users)
+		for i := range voters {
+			voters[i] = newUser(t, s)
+		}
+
+		r := rand.New(rand.NewSource(1))
+		scripts := make([][][]int, users)
+		want := make(map[types.SnippetId]types.Rating)
+		final := make(map[types.UserId]map[types.SnippetId]int)
+		for i, voter := range voters {
+			final[voter] = make(map[types.SnippetId]int)
+			for _, id := range snippetIds {
+				votes, last := votingScript(r)
+				scripts[i] = append(scripts[i], votes)
+				final[voter][id] = last
+				d := ratingDelta(0, last)
+				rating := want[id]
+				rating.Likes += d.Likes
+				rating.Dislikes += d.Dislikes
+				want[id] = rating
+			}
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, users*snippets)
+		for i, voter := range voters {
+			for j, id := range snippetIds {
+				wg.Add(1)
+				go func(voter types.UserId, id types.SnippetId, votes []int) {
+					defer wg.Done()
+					for _, v := range votes {
+						if err := s.Vote(voter, id, v); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}(voter, id, scripts[i][j])
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatal(err)
+		}
+
+		checkRatings := func(when string) {
+			t.Helper()
+			for _, id := range snippetIds {
+				got, err := s.GetSnippet(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Rating != want[id] {
+					t.Errorf("%s: snippet %d is rated %+v, want %+v", when, id, got.Rating, want[id])
+				}
+			}
+		}
+		checkRatings("after voting")
+
+		for _, voter := range voters {
+			votes, err := s.GetVotes(voter, snippetIds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range snippetIds {
+				vote, err := s.GetVote(voter, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if vote != final[voter][id] || votes[id] != final[voter][id] {
+					t.Errorf("user %d voted %d on snippet %d, GetVote says %d and GetVotes %d", voter, final[voter][id], id, vote, votes[id])
+				}
+			}
+		}
+
+		if _, err := s.ReconcileRatings(); err != nil {
+			t.Fatal(err)
+		}
+		checkRatings("after reconciling consistent ratings")
+
+		corruptRating(t, s, snippetIds[0])
+		fixed, err := s.ReconcileRatings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed < 1 {
+			t.Errorf("%d snippets fixed, want at least the corrupted one", fixed)
+		}
+		checkRatings("after reconciling a corrupted rating")
+	})
+}
+
+// corruptRating sets the counters of a snippet out of sync with its votes.
+func corruptRating(t *testing.T, s testStorage, snippet types.SnippetId) {
+	t.Helper()
+	switch s := s.(type) {
+	case *Memory:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i := range s.snippets {
+			if s.snippets[i].Id == snippet {
+				s.snippets[i].Rating.Likes += 100
+			}
+		}
+	case Postgres:
+		if _, err := s.db.Exec(`update snippet set likes = likes + 100 where id = $1`, snippet); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("unknown storage %T", s)
+	}
+}
+
+// reactionTarget adapts reactions to snippets and to comments to the same test.
+type reactionTarget struct {
+	add    func(user types.UserId, id uint, reaction types.Reaction) error
+	remove func(user types.UserId, id uint, reaction types.Reaction) error
+	get    func(user types.UserId, ids []uint) (map[uint]types.Reactions, error)
+}
+
+func TestReactions(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author, other := newUser(t, s), newUser(t, s)
+		first := addSnippet(t, s, types.Snippet{Author: author, Contents: "x"})
+		second := addSnippet(t, s, types.Snippet{Author: author, Contents: "y"})
+		var comments []types.CommentId
+		for _, snippet := range []types.SnippetId{first, second} {
+			id, err := s.AddComment(types.Comment{Contents: "z", Snippet: snippet, Revision: 1, Author: author})
+			if err != nil {
+				t.Fatal(err)
+			}
+			comments = append(comments, id)
+		}
+
+		targets := map[string]struct {
+			ids []uint
+			reactionTarget
+		}{
+			"snippet": {[]uint{uint(first), uint(second)}, reactionTarget{
+				add: func(user types.UserId, id uint, reaction types.Reaction) error {
+					return s.AddReaction(user, types.SnippetId(id), reaction)
+				},
+				remove: func(user types.UserId, id uint, reaction types.Reaction) error {
+					return s.RemoveReaction(user, types.SnippetId(id), reaction)
+				},
+				get: func(user types.UserId, ids []uint) (map[uint]types.Reactions, error) {
+					snippets := make([]types.SnippetId, len(ids))
+					for i, id := range ids {
+						snippets[i] = types.SnippetId(id)
+					}
+					r, err := s.GetReactions(user, snippets)
+					res := make(map[uint]types.Reactions, len(r))
+					for id, reactions := range r {
+						res[uint(id)] = reactions
+					}
+					return res, err
+				},
+			}},
+			"comment": {[]uint{uint(comments[0]), uint(comments[1])}, reactionTarget{
+				add: func(user types.UserId, id uint, reaction types.Reaction) error {
+					return s.AddCommentReaction(user, types.CommentId(id), reaction)
+				},
+				remove: func(user types.UserId, id uint, reaction types.Reaction) error {
+					return s.RemoveCommentReaction(user, types.CommentId(id), reaction)
+				},
+				get: func(user types.UserId, ids []uint) (map[uint]types.Reactions, error) {
+					comments := make([]types.CommentId, len(ids))
+					for i, id := range ids {
+						comments[i] = types.CommentId(id)
+					}
+					r, err := s.GetCommentReactions(user, comments)
+					res := make(map[uint]types.Reactions, len(r))
+					for id, reactions := range r {
+						res[uint(id)] = reactions
+					}
+					return res, err
+				},
+			}},
+		}
+
+		for name, target := range targets {
+			first, second := target.ids[0], target.ids[1]
+			steps := []struct {
+				add      bool
+				user     types.UserId
+				id       uint
+				reaction types.Reaction
+			}{
+				{true, author, first, "🔥"},
+				{true, author, first, "🔥"}, // Reacting twice the same way counts once
+				{true, author, first, "😂"},
+				{true, other, first, "🔥"},
+				{true, other, second, "🐛"},
+				{false, author, first, "😂"},
+				{false, other, second, "🤯"}, // Removing what isn't there does nothing
+			}
+			for _, step := range steps {
+				do := target.add
+				if !step.add {
+					do = target.remove
+				}
+				if err := do(step.user, step.id, step.reaction); err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+			}
+
+			want := map[types.UserId]map[uint]types.Reactions{
+				author: {
+					first:  {Counts: map[types.Reaction]int{"🔥": 2}, CurrentUser: []types.Reaction{"🔥"}},
+					second: {Counts: map[types.Reaction]int{"🐛": 1}},
+				},
+				other: {
+					first:  {Counts: map[types.Reaction]int{"🔥": 2}, CurrentUser: []types.Reaction{"🔥"}},
+					second: {Counts: map[types.Reaction]int{"🐛": 1}, CurrentUser: []types.Reaction{"🐛"}},
+				},
+			}
+			for viewer, wantReactions := range want {
+				got, err := target.get(viewer, target.ids)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				for id, w := range wantReactions {
+					if !sameReactions(got[id], w) {
+						t.Errorf("%s %d seen by user %d has reactions %+v, want %+v", name, id, viewer, got[id], w)
+					}
+				}
+			}
+		}
+	})
+}
+
+func sameReactions(a, b types.Reactions) bool {
+	if len(a.Counts) != len(b.Counts) || len(a.CurrentUser) != len(b.CurrentUser) {
+		return false
+	}
+	for r, n := range a.Counts {
+		if b.Counts[r] != n {
+			return false
+		}
+	}
+	mine := make(map[types.Reaction]bool)
+	for _, r := range a.CurrentUser {
+		mine[r] = true
+	}
+	for _, r := range b.CurrentUser {
+		if !mine[r] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDeletingSnippetsDeletesWhatBelongsToThem(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		deletions := map[string]func(snippet types.SnippetId) error{
+			"delete": s.DeleteSnippet,
+			"expiry": func(snippet types.SnippetId) error {
+				if burned, err := s.BurnSnippet(snippet); err != nil || !burned {
+					return fmt.Errorf("burned %v: %v", burned, err)
+				}
+				_, err := s.DeleteExpiredSnippets()
+				return err
+			},
+		}
+		for name, del := range deletions {
+			author, other := newUser(t, s), newUser(t, s)
+			snippet := addSnippet(t, s, types.Snippet{Author: author, Contents: "x", BurnAfterRead: true})
+			comment, err := s.AddComment(types.Comment{Contents: "y", Snippet: snippet, Revision: 1, Author: author})
+			if err != nil {
+				t.Fatal(err)
+			}
+			reply, err := s.AddComment(types.Comment{ParentId: comment, Depth: 1, Contents: "z", Snippet: snippet, Revision: 1, Author: other})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.UpdateComment(comment, "edited", "edited"); err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range []types.UserId{author, other} {
+				if err := s.Vote(user, snippet, 1); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.AddReaction(user, snippet, "🔥"); err != nil {
+					t.Fatal(err)
+				}
+				for _, c := range []types.CommentId{comment, reply} {
+					if err := s.VoteComment(user, c, 1); err != nil {
+						t.Fatal(err)
+					}
+					if err := s.AddCommentReaction(user, c, "🔥"); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			if err := del(snippet); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			comments := []types.CommentId{comment, reply}
+			for _, c := range comments {
+				if _, err := s.GetComment(c); err == nil {
+					t.Errorf("%s: comment %d is still there", name, c)
+				}
+			}
+			history, err := s.GetCommentHistory(comment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 0 {
+				t.Errorf("%s: comment %d still has %d revisions", name, comment, len(history))
+			}
+			commentVotes, err := s.GetCommentVotes(author, comments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			commentReactions, err := s.GetCommentReactions(author, comments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range comments {
+				if commentVotes[c] != 0 || len(commentReactions[c].Counts) != 0 {
+					t.Errorf("%s: comment %d still has vote %d and reactions %+v", name, c, commentVotes[c], commentReactions[c])
+				}
+			}
+			votes, err := s.GetVotes(author, []types.SnippetId{snippet})
+			if err != nil {
+				t.Fatal(err)
+			}
+			reactions, err := s.GetReactions(author, []types.SnippetId{snippet})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if votes[snippet] != 0 || len(reactions[snippet].Counts) != 0 {
+				t.Errorf("%s: snippet still has vote %d and reactions %+v", name, votes[snippet], reactions[snippet])
+			}
+		}
+	})
+}
+
+func TestSearchFollowsEditsAndDeletions(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		before, after, title := unique("before"), unique("after"), unique("title")
+		snippet := addSnippet(t, s, types.Snippet{Title: title, Author: author, Contents: "var " + before + " = 1"})
+
+		found := func(word string) bool {
+			t.Helper()
+			results, err := s.SearchSnippets(types.SearchQuery{Text: word, Limit: types.MaxPageLimit}, author)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				if r.Snippet.Id == snippet {
+					return true
+				}
+			}
+			return false
+		}
+		if !found(before) {
+			t.Fatalf("%q isn't found before the edit", before)
+		}
+
+		if _, err := s.UpdateSnippet(snippet, []types.File{{Name: "main.go", Contents: "var " + after + " = 1", Language: "go"}}); err != nil {
+			t.Fatal(err)
+		}
+		if found(before) {
+			t.Errorf("%q is still found after the edit", before)
+		}
+		if !found(after) || !found(title) {
+			t.Errorf("the edited snippet isn't found by %q and %q", after, title)
+		}
+
+		if err := s.DeleteSnippet(snippet); err != nil {
+			t.Fatal(err)
+		}
+		if found(after) || found(title) {
+			t.Errorf("the deleted snippet is still found")
+		}
+	})
+}
+
+func TestPagesDontSkipOrRepeatTiedSnippets(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		var posted []types.SnippetId
+		for i := 0; i < 7; i++ {
+			posted = append(posted, addSnippet(t, s, types.Snippet{Author: author, Contents: "x"}))
+		}
+
+		// Nobody voted, so every snippet has the same score and only ids tell them apart
+		for _, sort := range []types.SortOrder{types.SortTop, types.SortNewest, types.SortOldest} {
+			seen := make(map[types.SnippetId]bool)
+			var last types.Cursor
+			page := types.Page{Limit: 2, Sort: sort}
+			for pages := 0; pages <= len(posted); pages++ {
+				got, err := s.GetSnippetsByUser(author, author, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) == 0 {
+					break
+				}
+				if len(got) > page.Limit {
+					t.Fatalf("%s: %d snippets on a page of %d", sort, len(got), page.Limit)
+				}
+				for _, snippet := range got {
+					at := sort.SnippetCursor(snippet)
+					if seen[snippet.Id] {
+						t.Errorf("%s: snippet %d is listed twice", sort, snippet.Id)
+					}
+					if !last.IsZero() && !sort.Before(last, at) {
+						t.Errorf("%s: snippet %d at %+v goes after %+v", sort, snippet.Id, at, last)
+					}
+					seen[snippet.Id], last = true, at
+				}
+				page.After = last
+			}
+			if len(seen) != len(posted) {
+				t.Errorf("%s: %d snippets listed, want %d", sort, len(seen), len(posted))
+			}
+		}
+	})
+}
+
+func TestSearchCodePagesOnConfirmedMatches(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		word := unique("w")
+		match := addSnippet(t, s, types.Snippet{Author: author, Contents: "x := " + word + "(1)"})
+		// Newer snippets have the literal of the pattern, so that they pass the
+		// prefilter, but it's no word of its own there
+		for i := 0; i < codeSearchBatch+5; i++ {
+			addSnippet(t, s, types.Snippet{Author: author, Contents: "x := " + word + "suffix(1)"})
+		}
+
+		for _, pattern := range []string{`\b` + word + `\b`, `(?P<call>` + word + `)\(`} {
+			got, err := s.SearchCode(types.CodeSearchQuery{Mode: types.CodeSearchRegexp, Pattern: pattern, Author: author, Limit: 1}, author)
+			if err != nil {
+				t.Fatalf("%s: %v", pattern, err)
+			}
+			if len(got) != 1 || got[0].Id != match {
+				t.Errorf("%s: found %v, want %d", pattern, ids(got), match)
+			}
+		}
+
+		got, err := s.SearchCode(types.CodeSearchQuery{Mode: types.CodeSearchRegexp, Pattern: `Q*`, Author: author, Limit: types.MaxPageLimit}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("a pattern matching empty strings only found %d snippets, want none", len(got))
+		}
+	})
+}
+
+func TestRankingCandidatesAreBounded(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author, voter := newUser(t, s), newUser(t, s)
+		language := types.ProgrammingLanguage(unique("lang"))
+		var snippets []types.SnippetId
+		for i := 0; i < 10; i++ {
+			snippets = append(snippets, addSnippet(t, s, types.Snippet{Author: author, Contents: "x", Language: language}))
+		}
+		// The oldest is the best rated, the next one is the most disliked
+		if err := s.Vote(voter, snippets[0], 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Vote(voter, snippets[1], -1); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := s.GetRankingCandidates(language, author, time.Time{}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := ids(got)
+		if len(got) > 6 || !found[snippets[0]] || !found[snippets[9]] || !found[snippets[8]] || found[snippets[1]] {
+			t.Errorf("candidates are %v, want the best rated %d and the newest %d and %d, but not the disliked %d",
+				found, snippets[0], snippets[9], snippets[8], snippets[1])
+		}
+		for _, c := range got {
+			if c.Contents != "" {
+				t.Errorf("candidate %d comes with contents", c.Id)
+			}
+		}
+
+		listed, err := s.GetListedSnippets([]types.SnippetId{snippets[3], snippets[0], snippets[5]}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(listed) != 3 || listed[0].Id != snippets[3] || listed[1].Id != snippets[0] || listed[2].Id != snippets[5] || listed[0].Contents != "x" {
+			t.Errorf("got %+v, want snippets %d, %d and %d in order with contents", listed, snippets[3], snippets[0], snippets[5])
+		}
+	})
+}
+
+func TestEveryFileIsSearchedAndRevisioned(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		word, language := unique("w"), types.ProgrammingLanguage(unique("lang"))
+		id := addSnippet(t, s, types.Snippet{
+			Author:   author,
+			Filename: "main.go",
+			Contents: "package main",
+			Files: []types.File{
+				{Name: "main.go", Contents: "package main", Language: "go"},
+				{Name: "util.x", Contents: "call " + word + "(1)", Language: language},
+			},
+		})
+
+		for mode, pattern := range map[types.CodeSearchMode]string{
+			types.CodeSearchIdentifier: word,
+			types.CodeSearchRegexp:     word + `\(`,
+		} {
+			got, err := s.SearchCode(types.CodeSearchQuery{Mode: mode, Pattern: pattern, Language: language, Limit: types.MaxPageLimit}, author)
+			if err != nil {
+				t.Fatalf("%s: %v", pattern, err)
+			}
+			if !ids(got)[id] {
+				t.Errorf("%s: found %v, want %d", pattern, ids(got), id)
+			}
+		}
+		results, err := s.SearchSnippets(types.SearchQuery{Text: word, Limit: types.MaxPageLimit}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Snippet.Id != id {
+			t.Errorf("full-text search found %v, want %d", results, id)
+		}
+
+		byLanguage, err := s.GetSnippetsByLanguage(language, author, firstPage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ids(byLanguage)[id] {
+			t.Errorf("snippets in %s are %v, want %d", language, ids(byLanguage), id)
+		}
+		counts, err := s.CountSnippetsByLanguage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts[language] != 1 {
+			t.Errorf("%d snippets counted in %s, want 1", counts[language], language)
+		}
+
+		edited := []types.File{
+			{Name: "main.go", Contents: "package main", Language: "go"},
+			{Name: "util.x", Contents: "call other(1)", Language: language},
+		}
+		updated, err := s.UpdateSnippet(id, edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Revision != 2 {
+			t.Fatalf("revision %d after an edit, want 2", updated.Revision)
+		}
+		revisions, err := s.GetRevisions(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != 2 || len(revisions[0].Files) != 2 || len(revisions[1].Files) != 2 ||
+			revisions[0].Files[1].Contents != "call "+word+"(1)" || revisions[1].Files[1].Contents != "call other(1)" {
+			t.Fatalf("revisions are %+v, want both files of both revisions", revisions)
+		}
+		got, err := s.SearchCode(types.CodeSearchQuery{Mode: types.CodeSearchIdentifier, Pattern: word, Limit: types.MaxPageLimit}, author)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids(got)[id] {
+			t.Errorf("%s is still found after being edited out", word)
+		}
+
+		// Highlighting of a stale revision leaves the current files alone
+		if err := s.SetFileHighlight(id, 1, 1, "stale"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetFileHighlight(id, 2, 1, "fresh"); err != nil {
+			t.Fatal(err)
+		}
+		files, err := s.GetFiles([]types.SnippetId{id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files[id]) != 1 || files[id][0].HighlightedContents != "fresh" {
+			t.Errorf("files are %+v, want the second one highlighted by the current revision", files[id])
+		}
+		first, err := s.GetRevision(id, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Files[1].HighlightedContents != "stale" {
+			t.Errorf("the first revision has %q highlighted, want stale", first.Files[1].HighlightedContents)
+		}
+	})
+}
+
+func TestNormalizeLanguages(t *testing.T) {
+	forEachStorage(t, func(t *testing.T, s testStorage) {
+		author := newUser(t, s)
+		legacy := addSnippet(t, s, types.Snippet{Author: author, Contents: "package main", Language: "Go", Files: []types.File{
+			{Name: "main.go", Contents: "package main", Language: "Go"},
+			{Name: "main.py", Contents: "print(1)", Language: "Python"},
+			{Name: "notes", Contents: "nothing", Language: "no such language"},
+		}})
+		if _, err := s.UpdateSnippet(legacy, []types.File{{Name: "main.go", Contents: "package main\n", Language: "golang"}}); err != nil {
+			t.Fatal(err)
+		}
+		canonical := addSnippet(t, s, types.Snippet{Author: author, Contents: "package main", Language: "go"})
+
+		n, err := s.NormalizeLanguages()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n < 1 {
+			t.Errorf("languages of %d snippets normalized, want at least 1", n)
+		}
+
+		snippet, err := s.GetSnippet(legacy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snippet.Language != "go" {
+			t.Errorf("snippet is in %q, want go", snippet.Language)
+		}
+		revisions, err := s.GetRevisions(legacy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []types.ProgrammingLanguage
+		for _, r := range revisions {
+			got = append(got, r.Language)
+			for _, f := range r.Files {
+				got = append(got, f.Language)
+			}
+		}
+		want := []types.ProgrammingLanguage{"go", "go", "python", "text", "go", "go"}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("revisions are in %v, want %v", got, want)
+		}
+
+		if n, err = s.NormalizeLanguages(); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("normalizing again changed %d snippets, want none", n)
+		}
+		if snippet, err = s.GetSnippet(canonical); err != nil || snippet.Language != "go" {
+			t.Errorf("a snippet in go is in %q after normalizing, %v", snippet.Language, err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+type CollectionId uint
+
+// Collection is a named list of snippets put together by its owner.
+type Collection struct {
+	Id        CollectionId
+	Owner     UserId
+	Name      string
+	Public    bool      // Anyone may see a public collection, others are seen by the owner only
+	Snippets  []Snippet // In the owner's order, only filled when fetching a single collection
+	CreatedAt time.Time
+}
+
+// NewCollection holds everything a user provides when creating a collection.
+type NewCollection struct {
+	Name   string
+	Public bool
+}
+
+// CollectionEdit lists the collection fields to change, nil fields are left as they are.
+type CollectionEdit struct {
+	Name   *string
+	Public *bool
+}
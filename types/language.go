@@ -0,0 +1,16 @@
+package types
+
+// Language is a language snippets may be written in.
+type Language struct {
+	Id         ProgrammingLanguage // Canonical identifier, also the name of the pygmentize lexer
+	Name       string              // Display name
+	Aliases    []string            // Other names the language goes by, matched case-insensitively
+	Extensions []string            // File extensions with the leading dot
+	Filenames  []string            // Whole file names, for files without telling extensions
+}
+
+// LanguageCount is a language with the number of public snippets in it.
+type LanguageCount struct {
+	Language
+	Snippets int
+}
@@ -0,0 +1,131 @@
+package types
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidSortOrder = errors.New("invalid sort order")
+)
+
+type SortOrder string
+
+const (
+	SortNewest SortOrder = "newest"
+	SortOldest SortOrder = "oldest"
+	SortTop    SortOrder = "top" // Highest score first
+)
+
+// Cursor points right after the last item of a page.
+// The zero Cursor points at the beginning of a listing.
+type Cursor struct {
+	Key int64 // Sort key of the last item, e.g. its creation time in nanoseconds
+	Id  uint  // Id of the last item, breaks ties between equal keys
+}
+
+type Page struct {
+	After Cursor
+	Limit int
+	Sort  SortOrder
+}
+
+type SnippetPage struct {
+	Snippets   []Snippet
+	NextCursor string // Empty on the last page
+}
+
+type CommentPage struct {
+	Comments   []Comment
+	NextCursor string // Empty on the last page
+}
+
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch s {
+	case "":
+		return "", nil
+	case "new", string(SortNewest):
+		return SortNewest, nil
+	case "old", string(SortOldest):
+		return SortOldest, nil
+	case string(SortTop):
+		return SortTop, nil
+	}
+	return "", ErrInvalidSortOrder
+}
+
+// Descending reports whether listings sorted by o go from the largest key to the smallest.
+func (o SortOrder) Descending() bool {
+	return o != SortOldest
+}
+
+// Before reports whether position a goes before position b in listings sorted by o.
+func (o SortOrder) Before(a, b Cursor) bool {
+	if o.Descending() {
+		return b.Less(a)
+	}
+	return a.Less(b)
+}
+
+// SnippetCursor returns the position of a snippet in listings sorted by o.
+func (o SortOrder) SnippetCursor(s Snippet) Cursor {
+	if o == SortTop {
+		return Cursor{Key: int64(s.Rating.Likes - s.Rating.Dislikes), Id: uint(s.Id)}
+	}
+	return CursorByTime(s.CreatedAt, uint(s.Id))
+}
+
+// CommentCursor returns the position of a comment in listings sorted by o.
+func (o SortOrder) CommentCursor(c Comment) Cursor {
+	if o == SortTop {
+		return Cursor{Key: int64(c.Rating.Likes - c.Rating.Dislikes), Id: uint(c.Id)}
+	}
+	return CursorByTime(c.CreatedAt, uint(c.Id))
+}
+
+func CursorByTime(t time.Time, id uint) Cursor {
+	return Cursor{Key: t.UnixNano(), Id: id}
+}
+
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+func (c Cursor) Less(d Cursor) bool {
+	return c.Key < d.Key || c.Key == d.Key && c.Id < d.Id
+}
+
+func (c Cursor) Time() time.Time {
+	return time.Unix(0, c.Key).UTC()
+}
+
+// String encodes the cursor into an opaque token suitable for URLs.
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.Key, c.Id)))
+}
+
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if _, err := fmt.Sscanf(string(b), "%d:%d", &c.Key, &c.Id); err != nil || c.String() != s {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursors := []Cursor{
+		{Key: 1, Id: 1},
+		{Key: -5, Id: 42},
+		{Key: 0, Id: 7},
+		{Key: 1<<63 - 1, Id: 1<<32 - 1},
+		CursorByTime(time.Date(2021, 4, 1, 12, 30, 0, 123, time.UTC), 3),
+	}
+	for _, c := range cursors {
+		got, err := ParseCursor(c.String())
+		if err != nil {
+			t.Errorf("%+v: %v", c, err)
+			continue
+		}
+		if got != c {
+			t.Errorf("%+v comes back as %+v", c, got)
+		}
+	}
+
+	if got, err := ParseCursor(Cursor{}.String()); err != nil || !got.IsZero() {
+		t.Errorf("the zero cursor comes back as %+v, %v", got, err)
+	}
+	at := time.Date(2021, 4, 1, 12, 30, 0, 123, time.UTC)
+	if got := CursorByTime(at, 1).Time(); !got.Equal(at) {
+		t.Errorf("cursor time %v, want %v", got, at)
+	}
+}
+
+func TestInvalidCursorsAreRejected(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	for _, s := range []string{
+		"!!!",
+		encode("garbage"),
+		encode("1"),
+		encode("1:"),
+		encode("a:1"),
+		encode("1:-1"),
+		encode("01:1"),      // Another spelling of a valid cursor
+		encode("1:1 "),      // Trailing data
+		encode("1:1") + "=", // Padding
+		base64.StdEncoding.EncodeToString([]byte("1:1")) + "==",
+	} {
+		if c, err := ParseCursor(s); err != ErrInvalidCursor {
+			t.Errorf("ParseCursor(%q) = %+v, %v, want %v", s, c, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	tests := map[string]SortOrder{
+		"":       "",
+		"new":    SortNewest,
+		"newest": SortNewest,
+		"old":    SortOldest,
+		"oldest": SortOldest,
+		"top":    SortTop,
+	}
+	for s, want := range tests {
+		if got, err := ParseSortOrder(s); err != nil || got != want {
+			t.Errorf("ParseSortOrder(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+	if _, err := ParseSortOrder("best"); err != ErrInvalidSortOrder {
+		t.Errorf("ParseSortOrder(best): got %v, want %v", err, ErrInvalidSortOrder)
+	}
+}
@@ -0,0 +1,44 @@
+package types
+
+import "errors"
+
+type SearchQuery struct {
+	Text     string              // Words, "quoted phrases" and -excluded words
+	Language ProgrammingLanguage // Empty matches any language
+	Author   UserId              // Zero matches any author
+	Limit    int
+}
+
+type SearchResult struct {
+	Snippet   Snippet
+	Rank      float64  // Relevance for text search, the number of matches for code search
+	Fragments []string // HTML-escaped excerpts of the snippet and its comments with matches wrapped into <mark>
+}
+
+type CodeSearchMode string
+
+const (
+	CodeSearchIdentifier CodeSearchMode = "identifier" // Identifiers equal to the pattern or having it as a word
+	CodeSearchRegexp     CodeSearchMode = "regexp"
+)
+
+var ErrInvalidCodeSearchMode = errors.New("invalid code search mode")
+
+type CodeSearchQuery struct {
+	Mode     CodeSearchMode
+	Pattern  string
+	Language ProgrammingLanguage // Empty matches any language
+	Author   UserId              // Zero matches any author
+	Limit    int
+}
+
+// ParseCodeSearchMode defaults to identifier search.
+func ParseCodeSearchMode(s string) (CodeSearchMode, error) {
+	switch s {
+	case "", string(CodeSearchIdentifier):
+		return CodeSearchIdentifier, nil
+	case "regex", string(CodeSearchRegexp):
+		return CodeSearchRegexp, nil
+	}
+	return "", ErrInvalidCodeSearchMode
+}
@@ -1,17 +1,88 @@
 package types
 
+import "time"
+
 type SnippetStorage interface {
-	AddSnippet(snippet Snippet) (SnippetId, error)
-	GetSnippetsByUser(user UserId) ([]Snippet, error)
-	GetSnippetsByLanguage(language ProgrammingLanguage) ([]Snippet, error)
-	GetSnippet(snippet SnippetId) (Snippet, error)
+	AddSnippet(snippet Snippet) (SnippetId, error) // Stores Files after the first one, which is the one in Contents
+
+	// Listings contain public snippets and every snippet of the viewer, except for expired ones.
+	// Burn-after-read snippets are listed for their authors only.
+	GetSnippetsByUser(user UserId, viewer UserId, page Page) ([]Snippet, error)
+	GetSnippetsByLanguage(language ProgrammingLanguage, viewer UserId, page Page) ([]Snippet, error) // Snippets with a file in the language
+	GetFeed(user UserId, page Page) ([]Snippet, error)
+	GetForks(snippet SnippetId, viewer UserId, page Page) ([]Snippet, error)
+	GetSnippetsByTag(tag string, viewer UserId, page Page) ([]Snippet, error)
+	GetPopularTags(limit int) ([]TagCount, error)
+	CountSnippetsByLanguage() (map[ProgrammingLanguage]int, error) // Public snippets that haven't expired, by languages of any of their files
+	// Up to limit best rated, most liked and newest snippets each created since the given time, without contents
+	GetRankingCandidates(language ProgrammingLanguage, viewer UserId, since time.Time, limit int) ([]Snippet, error)
+	GetListedSnippets(snippets []SnippetId, viewer UserId) ([]Snippet, error) // In the given order
+
+	GetSnippet(snippet SnippetId) (Snippet, error) // ErrSnippetExpired if the snippet has been reaped
 	DeleteSnippet(snippet SnippetId) error
-	SetSnippetHighlight(snippet SnippetId, highlight string) error
+	BurnSnippet(snippet SnippetId) (bool, error)
+	DeleteExpiredSnippets() (int, error)
+	UpdateSnippet(snippet SnippetId, files []File) (Snippet, error) // Replaces every file and saves them as a new revision
+	UpdateSnippetDetails(snippet Snippet) error
+	SetSnippetHighlight(snippet SnippetId, revision int, highlight string) error
+	GetFiles(snippets []SnippetId) (map[SnippetId][]File, error)                        // Files after the one in Contents, in order
+	SetFileHighlight(snippet SnippetId, revision int, file int, highlight string) error // Files are numbered from 1, as the one in Contents is file 0
+	GetRevisions(snippet SnippetId) ([]Revision, error)
+	GetRevision(snippet SnippetId, revision int) (Revision, error)
 	Vote(user UserId, snippet SnippetId, vote int) error
 	GetVote(user UserId, snippet SnippetId) (int, error)
+	GetVotes(user UserId, snippets []SnippetId) (map[SnippetId]int, error)
+	AddReaction(user UserId, snippet SnippetId, reaction Reaction) error
+	RemoveReaction(user UserId, snippet SnippetId, reaction Reaction) error
+	GetReactions(user UserId, snippets []SnippetId) (map[SnippetId]Reactions, error)
 
 	AddComment(comment Comment) (CommentId, error)
 	GetComment(comment CommentId) (Comment, error)
-	GetComments(snippet SnippetId) ([]Comment, error)
+	GetComments(snippet SnippetId, page Page) ([]Comment, error)                 // Top-level comments only
+	GetReplies(comments []CommentId) ([]Comment, error)                          // Every descendant, oldest first
+	GetLineComments(snippet SnippetId) ([]Comment, error)                        // Top-level comments on lines, oldest first
+	UpdateComment(comment CommentId, contents, rendered string) (Comment, error) // Keeps the former contents in the history
+	GetCommentHistory(comment CommentId) ([]CommentRevision, error)              // Oldest first
 	DeleteComment(comment CommentId) error
+	TombstoneComment(comment CommentId) error
+	VoteComment(user UserId, comment CommentId, vote int) error
+	GetCommentVotes(user UserId, comments []CommentId) (map[CommentId]int, error)
+	AddCommentReaction(user UserId, comment CommentId, reaction Reaction) error
+	RemoveCommentReaction(user UserId, comment CommentId, reaction Reaction) error
+	GetCommentReactions(user UserId, comments []CommentId) (map[CommentId]Reactions, error)
+}
+
+// SearchStorage looks for snippets by their titles, descriptions, contents and
+// comments. Results contain snippets listed for the viewer, best matches first.
+type SearchStorage interface {
+	SearchSnippets(query SearchQuery, viewer UserId) ([]SearchResult, error)
+	// SearchCode returns snippets whose contents match the query, newest first.
+	// Contents match if they have non-empty matches of the pattern as Go understands it.
+	SearchCode(query CodeSearchQuery, viewer UserId) ([]Snippet, error)
+}
+
+type FollowStorage interface {
+	Follow(follower UserId, followee UserId) error
+	Unfollow(follower UserId, followee UserId) error
+	IsFollowing(follower UserId, followee UserId) (bool, error)
+	GetFollowers(user UserId) ([]User, error)
+	GetFollowing(user UserId) ([]User, error)
+}
+
+// CollectionStorage keeps bookmarks and collections. Deleted snippets drop out of both.
+type CollectionStorage interface {
+	AddBookmark(user UserId, snippet SnippetId) error
+	RemoveBookmark(user UserId, snippet SnippetId) error
+	GetBookmarks(user UserId) ([]Snippet, error) // Newest bookmarks first, without snippets hidden from the user, burning or expired
+
+	AddCollection(collection Collection) (CollectionId, error)
+	GetCollection(collection CollectionId) (Collection, error)
+	GetCollections(owner UserId) ([]Collection, error) // Oldest first
+	UpdateCollection(collection Collection) error
+	DeleteCollection(collection CollectionId) error
+	AddToCollection(collection CollectionId, snippet SnippetId) error // Appends the snippet unless it's there already
+	RemoveFromCollection(collection CollectionId, snippet SnippetId) error
+	GetCollectionItems(collection CollectionId) ([]SnippetId, error)                 // In order
+	GetCollectionSnippets(collection CollectionId, viewer UserId) ([]Snippet, error) // In order, without snippets hidden from the viewer, burning or expired
+	ReorderCollection(collection CollectionId, snippets []SnippetId) error
 }
@@ -2,20 +2,36 @@ package types
 
 import (
 	"errors"
+	"github.com/mp-hl-2021/splinter/diff"
 	"time"
 )
 
 type ProgrammingLanguage string
+type Visibility string
 type UserId uint
 type SnippetId uint
 type CommentId uint
+type Reaction string
 type Token string
 
 var (
-	ErrInvalidLogin    = errors.New("login not found")
-	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidLogin      = errors.New("login not found")
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrInvalidVisibility = errors.New("invalid visibility")
+	ErrSnippetExpired    = errors.New("snippet has expired")
+	ErrInvalidLineRange  = errors.New("invalid line range")
+	ErrDiffTooLarge      = errors.New("revisions are too large to compare")
 )
 
+const (
+	VisibilityPublic   Visibility = "public"
+	VisibilityUnlisted Visibility = "unlisted" // Reachable by link, but not listed
+	VisibilityPrivate  Visibility = "private"  // Reachable by the author only
+)
+
+// DefaultReactions are the reactions users may leave unless configured otherwise
+var DefaultReactions = []Reaction{"🤯", "😂", "🐛", "🔥"}
+
 type User struct {
 	Id       UserId // Unique identifier, persists through username changes
 	Username string // Visible username, can be changed
@@ -26,22 +42,199 @@ type Rating struct {
 	Dislikes int
 }
 
+// Reactions sums up reactions to a snippet or a comment.
+type Reactions struct {
+	Counts      map[Reaction]int // Reactions nobody has left are omitted
+	CurrentUser []Reaction       // Reactions left by the viewer
+}
+
+// LanguageGuess is a language detected from the contents of a file.
+type LanguageGuess struct {
+	Language   ProgrammingLanguage
+	Confidence float64 // From 0 to 1
+}
+
+// LineRange spans lines From to To inclusive, counting from 1.
+// The zero LineRange stands for the whole snippet.
+type LineRange struct {
+	From int
+	To   int
+}
+
 type Comment struct {
-	Id        CommentId
+	Id              CommentId
+	ParentId        CommentId // Zero for top-level comments
+	Depth           int       // Zero for top-level comments
+	Contents        string    // Markdown
+	Rendered        string    // Contents rendered to sanitized HTML
+	Snippet         SnippetId
+	Revision        int       // Revision of the snippet the comment was posted on
+	File            int       // Number of the file Lines are in, files are numbered from 0 in order
+	Lines           LineRange // Replies share the lines and the file of their thread
+	Author          UserId
+	Rating          Rating
+	CurrentUserVote int
+	Reactions       Reactions
+	Deleted         bool // Deleted comments with replies stay in the thread as tombstones
+	CreatedAt       time.Time
+	EditedAt        *time.Time // Nil unless the comment has been edited
+	Replies         []Comment
+}
+
+// CommentRevision is a former version of a comment, written at CreatedAt.
+type CommentRevision struct {
+	Comment   CommentId
 	Contents  string
-	Snippet   SnippetId
-	Author    UserId
 	CreatedAt time.Time
 }
 
 type Snippet struct {
 	Id                  SnippetId
+	Title               string
+	Description         string // Markdown
+	Tags                []string
+	Visibility          Visibility
+	Filename            string // Name of the file in Contents, may be empty unless the snippet has more files
 	Contents            string
 	HighlightedContents string
 	Language            ProgrammingLanguage
+	Files               []File         // Every file in order, starting with the one in Contents
+	DetectedLanguage    *LanguageGuess // Set only in response to posting without a language
 	Author              UserId
 	Rating              Rating
 	CurrentUserVote     int
+	Reactions           Reactions
+	Revision            int        // Number of the current revision, starts from 1
+	ForkedFrom          SnippetId  // Zero unless the snippet is a fork, kept after the parent is deleted
+	ExpiresAt           *time.Time // Nil unless the snippet expires
+	BurnAfterRead       bool       // Expires once read by anyone but the author
 	CreatedAt           time.Time
 }
 
+// File is one of the files of a multi-file snippet.
+type File struct {
+	Name                string
+	Contents            string
+	HighlightedContents string
+	Language            ProgrammingLanguage
+	DetectedLanguage    *LanguageGuess // Set only in response to posting without a language
+}
+
+type Revision struct {
+	Snippet             SnippetId
+	Number              int
+	Contents            string // Contents, highlighting and language of the first file
+	HighlightedContents string
+	Language            ProgrammingLanguage
+	Files               []File // Every file of the revision in order, starting with the first one
+	CreatedAt           time.Time
+}
+
+type RevisionDiff struct {
+	From  Revision
+	To    Revision
+	File  string // Name of the compared file, empty for the first one
+	Hunks []diff.Hunk
+}
+
+// NewSnippet holds everything a user provides when posting a snippet.
+type NewSnippet struct {
+	Title         string
+	Description   string
+	Tags          []string
+	Visibility    Visibility // Public if empty
+	TTL           time.Duration
+	BurnAfterRead bool
+	Filename      string
+	Contents      string
+	Language      ProgrammingLanguage // Detected from Filename and Contents if empty
+	Files         []File              // Every file of a multi-file snippet, replaces Filename, Contents and Language if set
+}
+
+// NewComment holds everything a user provides when posting a comment.
+type NewComment struct {
+	Snippet  SnippetId
+	ParentId CommentId // Zero for top-level comments
+	File     int       // Number of the file Lines are in, ignored for replies
+	Lines    LineRange // Zero for comments on the whole snippet, ignored for replies
+	Contents string
+}
+
+// LineComments are threads on lines of File ending at Line, which is where clients render them.
+type LineComments struct {
+	File     int
+	Line     int
+	Comments []Comment
+}
+
+// SnippetEdit lists the snippet fields to change, nil fields are left as they are.
+// Contents and Language are those of the first file of multi-file snippets.
+type SnippetEdit struct {
+	Title       *string
+	Description *string
+	Tags        *[]string
+	Visibility  *Visibility
+	Contents    *string
+	Language    *ProgrammingLanguage
+	Files       *[]File // Every file in order, replaces Contents and Language if set
+}
+
+func (r LineRange) IsZero() bool {
+	return r == LineRange{}
+}
+
+// Validate checks that the range is within a text of the given number of lines.
+func (r LineRange) Validate(lines int) error {
+	if r.IsZero() {
+		return nil
+	}
+	if r.From < 1 || r.To < r.From || r.To > lines {
+		return ErrInvalidLineRange
+	}
+	return nil
+}
+
+func ParseVisibility(s string) (Visibility, error) {
+	switch v := Visibility(s); v {
+	case "":
+		return VisibilityPublic, nil
+	case VisibilityPublic, VisibilityUnlisted, VisibilityPrivate:
+		return v, nil
+	}
+	return "", ErrInvalidVisibility
+}
+
+// MainFile returns the file in Contents.
+func (s Snippet) MainFile() File {
+	return File{
+		Name:                s.Filename,
+		Contents:            s.Contents,
+		HighlightedContents: s.HighlightedContents,
+		Language:            s.Language,
+		DetectedLanguage:    s.DetectedLanguage,
+	}
+}
+
+// File finds a file of the revision by name, the first file if name is empty.
+func (r Revision) File(name string) (File, bool) {
+	for i, f := range r.Files {
+		if name == "" && i == 0 || name != "" && f.Name == name {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+func (s Snippet) ExpiredAt(t time.Time) bool {
+	return s.ExpiresAt != nil && !t.Before(*s.ExpiresAt)
+}
+
+// VisibleTo reports whether user may open the snippet.
+func (s Snippet) VisibleTo(user UserId) bool {
+	return s.Visibility != VisibilityPrivate || s.Author == user
+}
+
+type TagCount struct {
+	Tag      string
+	Snippets int
+}
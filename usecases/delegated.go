@@ -3,23 +3,74 @@ package usecases
 import (
 	"errors"
 	"github.com/mp-hl-2021/splinter/auth"
+	"github.com/mp-hl-2021/splinter/diff"
 	"github.com/mp-hl-2021/splinter/highlighter"
+	"github.com/mp-hl-2021/splinter/langdetect"
+	"github.com/mp-hl-2021/splinter/languages"
+	"github.com/mp-hl-2021/splinter/markdown"
+	"github.com/mp-hl-2021/splinter/search"
 	"github.com/mp-hl-2021/splinter/types"
 	"golang.org/x/crypto/bcrypt"
 	"log"
+	"regexp"
+	"sort"
+	"time"
 )
 
 var (
-	MustBeSnippetAuthorErr = errors.New("must be snippet's author")
-	MustBeCommentAuthorErr = errors.New("must be comment's author")
-	InvalidVoteErr         = errors.New("invalid vote")
+	MustBeSnippetAuthorErr   = errors.New("must be snippet's author")
+	MustBeCommentAuthorErr   = errors.New("must be comment's author")
+	InvalidVoteErr           = errors.New("invalid vote")
+	CannotFollowSelfErr      = errors.New("cannot follow yourself")
+	AlreadyFollowingErr      = errors.New("already following this user")
+	NotFollowingErr          = errors.New("not following this user")
+	NoSuchSnippetErr         = errors.New("no such snippet")
+	EmptySearchQueryErr      = errors.New("search query is empty")
+	InvalidIdentifierErr     = errors.New("pattern must be a single identifier")
+	PatternTooLongErr        = errors.New("pattern is too long")
+	NoSuchCommentErr         = errors.New("no such comment")
+	ReplyTooDeepErr          = errors.New("reply is nested too deep")
+	MustBeModeratorErr       = errors.New("must be a moderator")
+	UnsupportedReactionErr   = errors.New("unsupported reaction")
+	NoSuchCollectionErr      = errors.New("no such collection")
+	NoSuchFileErr            = errors.New("no such file")
+	MustBeCollectionOwnerErr = errors.New("must be collection's owner")
+	InvalidOrderErr          = errors.New("order must list every snippet of the collection once")
+)
+
+// maxPatternLength bounds the work a single code search may cost the database
+const maxPatternLength = 256
+
+// maxCommentDepth is the depth of the deepest reply, top-level comments have depth zero
+const maxCommentDepth = 8
+
+// DeletedCommentContents replaces contents of comments deleted while having replies
+const DeletedCommentContents = "[deleted]"
+
+// diffContext is the number of unchanged lines shown around every change
+const diffContext = 3
+
+// rankingCandidates is the number of best rated, most liked and newest
+// snippets each that GetRankedSnippets ranks, rather than every snippet
+const rankingCandidates = 5 * types.MaxPageLimit
+
+// Revisions larger than these aren't compared, diffing costs time proportional
+// to the product of their sizes in the worst case
+const (
+	maxDiffLines = 5000
+	maxDiffBytes = 512 << 10
 )
 
 type DelegatedUserInterface struct {
-	Auth           auth.Authenticator
-	UserStorage    auth.UserStorage
-	SnippetStorage types.SnippetStorage
-	Highlighter    highlighter.Highlighter
+	Auth              auth.Authenticator
+	UserStorage       auth.UserStorage
+	SnippetStorage    types.SnippetStorage
+	FollowStorage     types.FollowStorage
+	SearchStorage     types.SearchStorage
+	CollectionStorage types.CollectionStorage
+	Highlighter       highlighter.Highlighter
+	Moderators        map[types.UserId]bool
+	Reactions         []types.Reaction // Reactions users may leave, DefaultReactions if empty
 }
 
 func (u *DelegatedUserInterface) CreateAccount(username, password string) (types.User, error) {
@@ -70,131 +121,1491 @@ func (u DelegatedUserInterface) GetUser(user types.UserId) (types.User, error) {
 	return types.User{Id: types.UserId(a.Id), Username: a.Username}, nil
 }
 
-func (u DelegatedUserInterface) PostSnippet(author types.UserId, contents string, language types.ProgrammingLanguage) (types.Snippet, error) {
+func (u DelegatedUserInterface) PostSnippet(author types.UserId, snippet types.NewSnippet) (types.Snippet, error) {
+	if err := ValidateTitle(snippet.Title); err != nil {
+		return types.Snippet{}, err
+	}
+	if err := ValidateDescription(snippet.Description); err != nil {
+		return types.Snippet{}, err
+	}
+	tags, err := NormalizeTags(snippet.Tags)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+	visibility, err := types.ParseVisibility(string(snippet.Visibility))
+	if err != nil {
+		return types.Snippet{}, err
+	}
+	if err := ValidateTTL(snippet.TTL); err != nil {
+		return types.Snippet{}, err
+	}
+	files := snippet.Files
+	if len(files) == 0 {
+		files = []types.File{{Name: snippet.Filename, Contents: snippet.Contents, Language: snippet.Language}}
+	}
+	if err := ValidateFiles(files); err != nil {
+		return types.Snippet{}, err
+	}
+	guesses := make([]*types.LanguageGuess, len(files))
+	for i := range files {
+		files[i].HighlightedContents = ""
+		if files[i].Language, err = NormalizeLanguage(files[i].Language); err != nil {
+			return types.Snippet{}, err
+		}
+		if files[i].Language == "" {
+			guess := langdetect.Detect(files[i].Name, files[i].Contents)
+			files[i].Language = guess.Language
+			guesses[i] = &guess
+		}
+	}
+
+	var expiresAt *time.Time
+	if snippet.TTL > 0 {
+		t := time.Now().Add(snippet.TTL)
+		expiresAt = &t
+	}
+
 	id, err := u.SnippetStorage.AddSnippet(types.Snippet{
-		Contents: contents,
-		Language: language,
-		Author:   author,
+		Title:         snippet.Title,
+		Description:   snippet.Description,
+		Tags:          tags,
+		Visibility:    visibility,
+		Filename:      files[0].Name,
+		Contents:      files[0].Contents,
+		Language:      files[0].Language,
+		Files:         files,
+		Author:        author,
+		ExpiresAt:     expiresAt,
+		BurnAfterRead: snippet.BurnAfterRead,
 	})
 
 	if err != nil {
 		return types.Snippet{}, err
 	}
 
-	snippet, err := u.SnippetStorage.GetSnippet(id)
+	s, err := u.SnippetStorage.GetSnippet(id)
 
 	if err != nil {
 		return types.Snippet{}, err
 	}
 
-	if err = u.Highlighter.Post(snippet); err != nil {
+	res := []types.Snippet{s}
+	if err := u.fillSnippets(author, res); err != nil {
+		return types.Snippet{}, err
+	}
+
+	if err = u.Highlighter.Post(res[0]); err != nil {
 		log.Printf("[WARN] Error when queueing highlighting task: %e", err)
 	}
 
-	return snippet, nil
+	res[0].DetectedLanguage = guesses[0]
+	for i := range res[0].Files {
+		res[0].Files[i].DetectedLanguage = guesses[i]
+	}
+	return res[0], nil
 }
 
-func (u DelegatedUserInterface) GetSnippetsByUser(user types.UserId, current types.UserId) ([]types.Snippet, error) {
-	s, err := u.SnippetStorage.GetSnippetsByUser(user)
+func (u DelegatedUserInterface) ForkSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error) {
+	parent, err := u.readSnippet(current, snippet)
 	if err != nil {
-		return []types.Snippet{}, err
+		return types.Snippet{}, err
 	}
 
-	for i := range s {
-		vote, err := u.SnippetStorage.GetVote(current, s[i].Id)
-		if err != nil {
-			return []types.Snippet{}, err
+	family := []types.Snippet{parent}
+	if err := u.fillFiles(family); err != nil {
+		return types.Snippet{}, err
+	}
+	parent = family[0]
+
+	id, err := u.SnippetStorage.AddSnippet(types.Snippet{
+		Title:       parent.Title,
+		Description: parent.Description,
+		Tags:        parent.Tags,
+		Visibility:  parent.Visibility,
+		Filename:    parent.Filename,
+		Contents:    parent.Contents,
+		Language:    parent.Language,
+		Files:       parent.Files,
+		Author:      current,
+		ForkedFrom:  parent.Id,
+	})
+
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	fork, err := u.SnippetStorage.GetSnippet(id)
+
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	// The fork has the same contents, so there is no need to highlight them again.
+	// Other files come with their highlighting, Post skips the highlighted ones.
+	if parent.HighlightedContents != "" {
+		err = u.SnippetStorage.SetSnippetHighlight(fork.Id, fork.Revision, parent.HighlightedContents)
+		if err == nil {
+			fork.HighlightedContents = parent.HighlightedContents
+		} else {
+			log.Printf("[WARN] Error when copying highlighting of snippet %d: %e", parent.Id, err)
 		}
-		s[i].CurrentUserVote = vote
 	}
 
-	return s, nil
+	res := []types.Snippet{fork}
+	if err := u.fillSnippets(current, res); err != nil {
+		return types.Snippet{}, err
+	}
+
+	if err = u.Highlighter.Post(res[0]); err != nil {
+		log.Printf("[WARN] Error when queueing highlighting task: %e", err)
+	}
+
+	return res[0], nil
+}
+
+func (u DelegatedUserInterface) GetForks(snippet types.SnippetId, current types.UserId, page types.Page) (types.SnippetPage, error) {
+	page = normalizePage(page)
+	s, err := u.SnippetStorage.GetForks(snippet, current, lookahead(page))
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	return makeSnippetPage(s, page), nil
+}
+
+func (u DelegatedUserInterface) GetSnippetsByTag(tag string, current types.UserId, page types.Page) (types.SnippetPage, error) {
+	tags, err := NormalizeTags([]string{tag})
+	if err != nil || len(tags) == 0 {
+		return types.SnippetPage{}, InvalidTagErr
+	}
+
+	page = normalizePage(page)
+	s, err := u.SnippetStorage.GetSnippetsByTag(tags[0], current, lookahead(page))
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	return makeSnippetPage(s, page), nil
+}
+
+func (u DelegatedUserInterface) GetPopularTags(limit int) ([]types.TagCount, error) {
+	return u.SnippetStorage.GetPopularTags(normalizePage(types.Page{Limit: limit}).Limit)
+}
+
+func (u DelegatedUserInterface) GetSnippetsByUser(user types.UserId, current types.UserId, page types.Page) (types.SnippetPage, error) {
+	page = normalizePage(page)
+	s, err := u.SnippetStorage.GetSnippetsByUser(user, current, lookahead(page))
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	return makeSnippetPage(s, page), nil
+}
+
+// GetLanguages lists every supported language with the number of public
+// snippets in it, the most used languages first.
+func (u DelegatedUserInterface) GetLanguages() ([]types.LanguageCount, error) {
+	counts, err := u.SnippetStorage.CountSnippetsByLanguage()
+	if err != nil {
+		return []types.LanguageCount{}, err
+	}
+
+	res := make([]types.LanguageCount, len(languages.All))
+	for i, l := range languages.All {
+		res[i] = types.LanguageCount{Language: l, Snippets: counts[l.Id]}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Snippets > res[j].Snippets
+	})
+	return res, nil
+}
+
+func (u DelegatedUserInterface) GetSnippetsByLanguage(language types.ProgrammingLanguage, current types.UserId, page types.Page) (types.SnippetPage, error) {
+	language, err := NormalizeLanguage(language)
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	page = normalizePage(page)
+	s, err := u.SnippetStorage.GetSnippetsByLanguage(language, current, lookahead(page))
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	return makeSnippetPage(s, page), nil
+}
+
+func (u DelegatedUserInterface) GetFeed(current types.UserId, page types.Page) (types.SnippetPage, error) {
+	page = normalizePage(page)
+	page.Sort = types.SortNewest
+	s, err := u.SnippetStorage.GetFeed(current, lookahead(page))
+	if err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return types.SnippetPage{}, err
+	}
+
+	return makeSnippetPage(s, page), nil
 }
 
-func (u DelegatedUserInterface) GetSnippetsByLanguage(language types.ProgrammingLanguage, current types.UserId) ([]types.Snippet, error) {
-	s, err := u.SnippetStorage.GetSnippetsByLanguage(language)
+func (u DelegatedUserInterface) GetRankedSnippets(current types.UserId, query RankingQuery) ([]types.Snippet, error) {
+	now := time.Now()
+	var since time.Time
+	if query.Window > 0 {
+		since = now.Add(-query.Window)
+	}
+
+	language, err := NormalizeLanguage(query.Language)
 	if err != nil {
 		return []types.Snippet{}, err
 	}
 
-	for i := range s {
-		vote, err := u.SnippetStorage.GetVote(current, s[i].Id)
-		if err != nil {
-			return []types.Snippet{}, err
+	s, err := u.SnippetStorage.GetRankingCandidates(language, current, since, rankingCandidates)
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	scores := make(map[types.SnippetId]float64, len(s))
+	for _, snippet := range s {
+		scores[snippet.Id] = query.Ranking.Score(snippet, now)
+	}
+
+	sort.Slice(s, func(i, j int) bool {
+		if scores[s[i].Id] != scores[s[j].Id] {
+			return scores[s[i].Id] > scores[s[j].Id]
 		}
-		s[i].CurrentUserVote = vote
+		return s[i].Id > s[j].Id
+	})
+
+	limit := normalizePage(types.Page{Limit: query.Limit}).Limit
+	if len(s) > limit {
+		s = s[:limit]
+	}
+
+	// Candidates come without contents
+	ids := make([]types.SnippetId, len(s))
+	for i := range s {
+		ids[i] = s[i].Id
+	}
+	if s, err = u.SnippetStorage.GetListedSnippets(ids, current); err != nil {
+		return []types.Snippet{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return []types.Snippet{}, err
 	}
 
 	return s, nil
 }
 
-func (u DelegatedUserInterface) GetSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error) {
-	s, err := u.SnippetStorage.GetSnippet(snippet)
+func (u DelegatedUserInterface) SearchSnippets(current types.UserId, query types.SearchQuery) ([]types.SearchResult, error) {
+	if search.ParseQuery(query.Text).IsEmpty() {
+		return []types.SearchResult{}, EmptySearchQueryErr
+	}
+	language, err := NormalizeLanguage(query.Language)
 	if err != nil {
-		return types.Snippet{}, err
+		return []types.SearchResult{}, err
 	}
+	query.Language = language
+	query.Limit = normalizePage(types.Page{Limit: query.Limit}).Limit
 
-	vote, err := u.SnippetStorage.GetVote(current, s.Id)
+	res, err := u.SearchStorage.SearchSnippets(query, current)
 	if err != nil {
-		return types.Snippet{}, err
+		return []types.SearchResult{}, err
 	}
 
-	s.CurrentUserVote = vote
-	return s, nil
+	s := make([]types.Snippet, len(res))
+	for i := range res {
+		s[i] = res[i].Snippet
+	}
+	if err := u.fillSnippets(current, s); err != nil {
+		return []types.SearchResult{}, err
+	}
+	for i := range res {
+		res[i].Snippet = s[i]
+	}
+
+	return res, nil
 }
 
-func (u DelegatedUserInterface) DeleteSnippet(current types.UserId, snippet types.SnippetId) error {
-	s, err := u.SnippetStorage.GetSnippet(snippet)
+func (u DelegatedUserInterface) SearchCode(current types.UserId, query types.CodeSearchQuery) ([]types.SearchResult, error) {
+	if len(query.Pattern) > maxPatternLength {
+		return []types.SearchResult{}, PatternTooLongErr
+	}
+
+	var matches func(contents string) [][]int
+	switch query.Mode {
+	case types.CodeSearchIdentifier:
+		if ids := search.Identifiers(query.Pattern); len(ids) != 1 || ids[0].Start != 0 || ids[0].End != len(query.Pattern) {
+			return []types.SearchResult{}, InvalidIdentifierErr
+		}
+		matches = func(contents string) [][]int {
+			return search.IdentifierMatches(contents, query.Pattern)
+		}
+	case types.CodeSearchRegexp:
+		re, err := regexp.Compile(query.Pattern)
+		if err != nil {
+			return []types.SearchResult{}, err
+		}
+		matches = func(contents string) [][]int {
+			return search.RegexpMatches(contents, re)
+		}
+	default:
+		return []types.SearchResult{}, types.ErrInvalidCodeSearchMode
+	}
+	language, err := NormalizeLanguage(query.Language)
 	if err != nil {
+		return []types.SearchResult{}, err
+	}
+	query.Language = language
+	query.Limit = normalizePage(types.Page{Limit: query.Limit}).Limit
+
+	s, err := u.SearchStorage.SearchCode(query, current)
+	if err != nil {
+		return []types.SearchResult{}, err
+	}
+	if err := u.fillSnippets(current, s); err != nil {
+		return []types.SearchResult{}, err
+	}
+
+	res := make([]types.SearchResult, 0, len(s))
+	for _, snippet := range s {
+		var rank float64
+		var headlines []string
+		for _, f := range snippet.Files {
+			if m := matches(f.Contents); len(m) > 0 {
+				rank += float64(len(m))
+				headlines = append(headlines, search.LineHeadline(f.Contents, m))
+			}
+		}
+		res = append(res, types.SearchResult{
+			Snippet:   snippet,
+			Rank:      rank,
+			Fragments: search.RenderFragments(headlines...),
+		})
+	}
+
+	return res, nil
+}
+
+// fillSnippets sets files, reactions and the viewer's vote of every snippet.
+func (u DelegatedUserInterface) fillSnippets(current types.UserId, s []types.Snippet) error {
+	if err := u.fillFiles(s); err != nil {
+		return err
+	}
+	if err := u.fillVotes(current, s); err != nil {
 		return err
 	}
+	return u.fillReactions(current, s)
+}
 
-	if s.Author != current {
-		return MustBeSnippetAuthorErr
+// fillFiles sets Files of every snippet with a single storage lookup.
+func (u DelegatedUserInterface) fillFiles(s []types.Snippet) error {
+	if len(s) == 0 {
+		return nil
 	}
 
-	return u.SnippetStorage.DeleteSnippet(snippet)
+	ids := make([]types.SnippetId, len(s))
+	for i := range s {
+		ids[i] = s[i].Id
+	}
+
+	files, err := u.SnippetStorage.GetFiles(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range s {
+		s[i].Files = append([]types.File{s[i].MainFile()}, files[s[i].Id]...)
+	}
+	return nil
 }
 
-func (u DelegatedUserInterface) Vote(current types.UserId, snippet types.SnippetId, vote int) error {
-	if vote < -1 || vote > 1 {
-		return InvalidVoteErr
+// fillVotes sets CurrentUserVote of every snippet with a single storage lookup.
+func (u DelegatedUserInterface) fillVotes(current types.UserId, s []types.Snippet) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	ids := make([]types.SnippetId, len(s))
+	for i := range s {
+		ids[i] = s[i].Id
 	}
 
-	if _, err := u.SnippetStorage.GetSnippet(snippet); err != nil {
+	votes, err := u.SnippetStorage.GetVotes(current, ids)
+	if err != nil {
 		return err
 	}
 
-	return u.SnippetStorage.Vote(current, snippet, vote)
+	for i := range s {
+		s[i].CurrentUserVote = votes[s[i].Id]
+	}
+	return nil
 }
 
-func (u DelegatedUserInterface) PostComment(author types.UserId, contents string, snippet types.SnippetId) (types.Comment, error) {
-	id, err := u.SnippetStorage.AddComment(types.Comment{
-		Contents: contents,
-		Snippet:  snippet,
-		Author:   author,
-	})
+// fillCommentVotes sets CurrentUserVote of every comment in lists with a single storage lookup.
+func (u DelegatedUserInterface) fillCommentVotes(current types.UserId, lists ...[]types.Comment) error {
+	var ids []types.CommentId
+	for _, c := range lists {
+		for i := range c {
+			ids = append(ids, c[i].Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
 
+	votes, err := u.SnippetStorage.GetCommentVotes(current, ids)
 	if err != nil {
-		return types.Comment{}, err
+		return err
 	}
 
-	return u.SnippetStorage.GetComment(id)
+	for _, c := range lists {
+		for i := range c {
+			c[i].CurrentUserVote = votes[c[i].Id]
+		}
+	}
+	return nil
 }
 
-func (u DelegatedUserInterface) GetComments(snippet types.SnippetId) ([]types.Comment, error) {
-	return u.SnippetStorage.GetComments(snippet)
+// fillReactions sets Reactions of every snippet with a single storage lookup.
+func (u DelegatedUserInterface) fillReactions(current types.UserId, s []types.Snippet) error {
+	if len(s) == 0 {
+		return nil
+	}
+
+	ids := make([]types.SnippetId, len(s))
+	for i := range s {
+		ids[i] = s[i].Id
+	}
+
+	reactions, err := u.SnippetStorage.GetReactions(current, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range s {
+		s[i].Reactions = u.summarizeReactions(reactions[s[i].Id])
+	}
+	return nil
 }
 
-func (u DelegatedUserInterface) DeleteComment(current types.UserId, comment types.CommentId) error {
-	c, err := u.SnippetStorage.GetComment(comment)
+// fillCommentReactions sets Reactions of every comment in lists with a single storage lookup.
+func (u DelegatedUserInterface) fillCommentReactions(current types.UserId, lists ...[]types.Comment) error {
+	var ids []types.CommentId
+	for _, c := range lists {
+		for i := range c {
+			ids = append(ids, c[i].Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	reactions, err := u.SnippetStorage.GetCommentReactions(current, ids)
 	if err != nil {
 		return err
 	}
 
-	if c.Author != current {
-		return MustBeCommentAuthorErr
+	for _, c := range lists {
+		for i := range c {
+			c[i].Reactions = u.summarizeReactions(reactions[c[i].Id])
+		}
+	}
+	return nil
+}
+
+// summarizeReactions drops reactions that are no longer allowed and
+// orders the viewer's reactions the same way as GetReactions.
+func (u DelegatedUserInterface) summarizeReactions(r types.Reactions) types.Reactions {
+	mine := make(map[types.Reaction]bool, len(r.CurrentUser))
+	for _, reaction := range r.CurrentUser {
+		mine[reaction] = true
+	}
+
+	res := types.Reactions{Counts: make(map[types.Reaction]int)}
+	for _, reaction := range u.GetReactions() {
+		if n := r.Counts[reaction]; n > 0 {
+			res.Counts[reaction] = n
+		}
+		if mine[reaction] {
+			res.CurrentUser = append(res.CurrentUser, reaction)
+		}
+	}
+	return res
+}
+
+func normalizePage(page types.Page) types.Page {
+	if page.Limit <= 0 {
+		page.Limit = types.DefaultPageLimit
+	}
+	if page.Limit > types.MaxPageLimit {
+		page.Limit = types.MaxPageLimit
+	}
+	if page.Sort == "" {
+		page.Sort = types.SortNewest
+	}
+	return page
+}
+
+// lookahead asks storage for one extra item which only signals that there is a next page.
+func lookahead(page types.Page) types.Page {
+	page.Limit++
+	return page
+}
+
+func makeSnippetPage(s []types.Snippet, page types.Page) types.SnippetPage {
+	if len(s) <= page.Limit {
+		return types.SnippetPage{Snippets: s}
+	}
+	s = s[:page.Limit]
+	return types.SnippetPage{
+		Snippets:   s,
+		NextCursor: page.Sort.SnippetCursor(s[len(s)-1]).String(),
+	}
+}
+
+func makeCommentPage(c []types.Comment, page types.Page) types.CommentPage {
+	if len(c) <= page.Limit {
+		return types.CommentPage{Comments: c}
+	}
+	c = c[:page.Limit]
+	return types.CommentPage{
+		Comments:   c,
+		NextCursor: page.Sort.CommentCursor(c[len(c)-1]).String(),
+	}
+}
+
+// getVisibleSnippet hides private snippets from everyone except their authors
+// as if they didn't exist. Expired snippets that are yet to be reaped are
+// reported the same way as the reaped ones.
+func (u DelegatedUserInterface) getVisibleSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error) {
+	s, err := u.SnippetStorage.GetSnippet(snippet)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	if !s.VisibleTo(current) {
+		return types.Snippet{}, NoSuchSnippetErr
+	}
+
+	if s.ExpiredAt(time.Now()) {
+		return types.Snippet{}, types.ErrSnippetExpired
+	}
+
+	return s, nil
+}
+
+// readSnippet is getVisibleSnippet for use cases revealing the contents of
+// the snippet: the first reader other than the author burns a burn-after-read
+// snippet, everyone after them finds it expired.
+func (u DelegatedUserInterface) readSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error) {
+	s, err := u.getVisibleSnippet(current, snippet)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	if s.BurnAfterRead && s.Author != current {
+		burnt, err := u.SnippetStorage.BurnSnippet(s.Id)
+		if err != nil {
+			return types.Snippet{}, err
+		}
+		if !burnt {
+			return types.Snippet{}, types.ErrSnippetExpired
+		}
+	}
+
+	return s, nil
+}
+
+func (u DelegatedUserInterface) GetSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error) {
+	s, err := u.readSnippet(current, snippet)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	res := []types.Snippet{s}
+	if err := u.fillSnippets(current, res); err != nil {
+		return types.Snippet{}, err
+	}
+	return res[0], nil
+}
+
+// GetFiles returns every file of the snippet in order.
+func (u DelegatedUserInterface) GetFiles(current types.UserId, snippet types.SnippetId) ([]types.File, error) {
+	s, err := u.readSnippet(current, snippet)
+	if err != nil {
+		return []types.File{}, err
+	}
+
+	res := []types.Snippet{s}
+	if err := u.fillFiles(res); err != nil {
+		return []types.File{}, err
+	}
+	return res[0].Files, nil
+}
+
+func (u DelegatedUserInterface) GetFile(current types.UserId, snippet types.SnippetId, name string) (types.File, error) {
+	files, err := u.GetFiles(current, snippet)
+	if err != nil {
+		return types.File{}, err
+	}
+
+	for _, f := range files {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return types.File{}, NoSuchFileErr
+}
+
+func (u DelegatedUserInterface) EditSnippet(current types.UserId, snippet types.SnippetId, edit types.SnippetEdit) (types.Snippet, error) {
+	s, err := u.getVisibleSnippet(current, snippet)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	if s.Author != current {
+		return types.Snippet{}, MustBeSnippetAuthorErr
+	}
+
+	title, description, tags, visibility := s.Title, s.Description, s.Tags, s.Visibility
+	if edit.Title != nil {
+		if err := ValidateTitle(*edit.Title); err != nil {
+			return types.Snippet{}, err
+		}
+		title = *edit.Title
+	}
+	if edit.Description != nil {
+		if err := ValidateDescription(*edit.Description); err != nil {
+			return types.Snippet{}, err
+		}
+		description = *edit.Description
+	}
+	if edit.Tags != nil {
+		if tags, err = NormalizeTags(*edit.Tags); err != nil {
+			return types.Snippet{}, err
+		}
+	}
+	if edit.Visibility != nil {
+		if visibility, err = types.ParseVisibility(string(*edit.Visibility)); err != nil {
+			return types.Snippet{}, err
+		}
+	}
+
+	if edit.Title != nil || edit.Description != nil || edit.Tags != nil || edit.Visibility != nil {
+		s.Title, s.Description, s.Tags, s.Visibility = title, description, tags, visibility
+		if err := u.SnippetStorage.UpdateSnippetDetails(s); err != nil {
+			return types.Snippet{}, err
+		}
+	}
+
+	res := []types.Snippet{s}
+	if err := u.fillFiles(res); err != nil {
+		return types.Snippet{}, err
+	}
+	old := res[0].Files
+	files, err := editedFiles(old, edit)
+	if err != nil {
+		return types.Snippet{}, err
+	}
+
+	if !sameFiles(old, files) {
+		if s, err = u.SnippetStorage.UpdateSnippet(snippet, files); err != nil {
+			return types.Snippet{}, err
+		}
+		res = []types.Snippet{s}
+		if err := u.fillFiles(res); err != nil {
+			return types.Snippet{}, err
+		}
+
+		if err = u.Highlighter.Post(res[0]); err != nil {
+			log.Printf("[WARN] Error when queueing highlighting task: %e", err)
+		}
+	}
+
+	if err := u.fillSnippets(current, res); err != nil {
+		return types.Snippet{}, err
+	}
+	return res[0], nil
+}
+
+// editedFiles applies the edit to the current files of a snippet. Files that
+// keep their name, contents and language keep their highlighting too.
+func editedFiles(old []types.File, edit types.SnippetEdit) ([]types.File, error) {
+	var files []types.File
+	if edit.Files != nil {
+		files = append(files, *edit.Files...)
+	} else {
+		files = append(files, old...)
+		if edit.Contents != nil {
+			files[0].Contents = *edit.Contents
+		}
+		if edit.Language != nil {
+			files[0].Language = *edit.Language
+		}
+	}
+	if err := ValidateFiles(files); err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		f := &files[i]
+		f.DetectedLanguage = nil
+		if edit.Files != nil || i == 0 && edit.Language != nil {
+			language, err := NormalizeLanguage(f.Language)
+			if err != nil {
+				return nil, err
+			}
+			if language == "" {
+				language = langdetect.Detect(f.Name, f.Contents).Language
+			}
+			f.Language = language
+		}
+		f.HighlightedContents = ""
+		if i < len(old) && f.Name == old[i].Name && f.Contents == old[i].Contents && f.Language == old[i].Language {
+			f.HighlightedContents = old[i].HighlightedContents
+		}
+	}
+	return files, nil
+}
+
+// sameFiles reports whether files have the same names, contents and languages.
+func sameFiles(a, b []types.File) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Contents != b[i].Contents || a[i].Language != b[i].Language {
+			return false
+		}
+	}
+	return true
+}
+
+func (u DelegatedUserInterface) GetRevisions(current types.UserId, snippet types.SnippetId) ([]types.Revision, error) {
+	if _, err := u.readSnippet(current, snippet); err != nil {
+		return []types.Revision{}, err
+	}
+
+	return u.SnippetStorage.GetRevisions(snippet)
+}
+
+func (u DelegatedUserInterface) GetRevision(current types.UserId, snippet types.SnippetId, revision int) (types.Revision, error) {
+	if _, err := u.readSnippet(current, snippet); err != nil {
+		return types.Revision{}, err
+	}
+
+	return u.SnippetStorage.GetRevision(snippet, revision)
+}
+
+// DiffRevisions compares a file in two revisions of a snippet, the first file if
+// file is empty. Zero to stands for the current revision, zero from for the
+// one right before to. A file missing from one of the revisions is compared as
+// an empty one.
+func (u DelegatedUserInterface) DiffRevisions(current types.UserId, snippet types.SnippetId, file string, from, to int) (types.RevisionDiff, error) {
+	s, err := u.readSnippet(current, snippet)
+	if err != nil {
+		return types.RevisionDiff{}, err
+	}
+
+	if to == 0 {
+		to = s.Revision
+	}
+
+	toRev, err := u.SnippetStorage.GetRevision(snippet, to)
+	if err != nil {
+		return types.RevisionDiff{}, err
+	}
+
+	// The first revision is compared against an empty one
+	fromRev := types.Revision{Snippet: snippet, Language: toRev.Language}
+	if from == 0 {
+		from = to - 1
+	}
+	if from > 0 {
+		if fromRev, err = u.SnippetStorage.GetRevision(snippet, from); err != nil {
+			return types.RevisionDiff{}, err
+		}
+	}
+
+	oldFile, oldOk := fromRev.File(file)
+	newFile, newOk := toRev.File(file)
+	if !oldOk && !newOk {
+		return types.RevisionDiff{}, NoSuchFileErr
+	}
+
+	oldLines, newLines := diff.SplitLines(oldFile.Contents), diff.SplitLines(newFile.Contents)
+	if len(oldLines) > maxDiffLines || len(newLines) > maxDiffLines ||
+		len(oldFile.Contents) > maxDiffBytes || len(newFile.Contents) > maxDiffBytes {
+		return types.RevisionDiff{}, types.ErrDiffTooLarge
+	}
+
+	lines := diff.Lines(oldLines, newLines)
+	return types.RevisionDiff{
+		From:  fromRev,
+		To:    toRev,
+		File:  file,
+		Hunks: diff.Hunks(lines, diffContext),
+	}, nil
+}
+
+func (u DelegatedUserInterface) RenderSideBySideDiff(d types.RevisionDiff) string {
+	oldFile, _ := d.From.File(d.File)
+	newFile, _ := d.To.File(d.File)
+	oldLines := u.highlightDiffLines(d.From, oldFile)
+	newLines := u.highlightDiffLines(d.To, newFile)
+	return diff.SideBySide(d.Hunks, oldLines, newLines)
+}
+
+// highlightDiffLines highlights a file of a revision line by line. Diffs index
+// the lines by their numbers, so unless every line of the file has a line of
+// its own, which backends dropping blank lines at the ends break, the lines
+// are left for diff.SideBySide to render as plain text.
+func (u DelegatedUserInterface) highlightDiffLines(r types.Revision, f types.File) []string {
+	lines, err := u.Highlighter.HighlightLines(f.Contents, f.Language)
+	if err != nil {
+		log.Printf("[WARN] Error when highlighting revision %d of snippet %d: %e", r.Number, r.Snippet, err)
+		return nil
+	}
+	if len(lines) != len(diff.SplitLines(f.Contents)) {
+		return nil
+	}
+	return lines
+}
+
+func (u DelegatedUserInterface) DeleteSnippet(current types.UserId, snippet types.SnippetId) error {
+	s, err := u.getVisibleSnippet(current, snippet)
+	if err != nil {
+		return err
+	}
+
+	if s.Author != current {
+		return MustBeSnippetAuthorErr
+	}
+
+	return u.SnippetStorage.DeleteSnippet(snippet)
+}
+
+func (u DelegatedUserInterface) Vote(current types.UserId, snippet types.SnippetId, vote int) error {
+	if vote < -1 || vote > 1 {
+		return InvalidVoteErr
+	}
+
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.Vote(current, snippet, vote)
+}
+
+func (u DelegatedUserInterface) VoteComment(current types.UserId, comment types.CommentId, vote int) error {
+	if vote < -1 || vote > 1 {
+		return InvalidVoteErr
+	}
+
+	c, err := u.SnippetStorage.GetComment(comment)
+	if err != nil {
+		return err
+	}
+
+	if c.Deleted {
+		return NoSuchCommentErr
+	}
+
+	if _, err := u.getVisibleSnippet(current, c.Snippet); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.VoteComment(current, comment, vote)
+}
+
+// GetReactions lists the reactions users may leave, in the order clients should show them.
+func (u DelegatedUserInterface) GetReactions() []types.Reaction {
+	if len(u.Reactions) == 0 {
+		return types.DefaultReactions
+	}
+	return u.Reactions
+}
+
+func (u DelegatedUserInterface) checkReaction(reaction types.Reaction) error {
+	for _, r := range u.GetReactions() {
+		if r == reaction {
+			return nil
+		}
+	}
+	return UnsupportedReactionErr
+}
+
+func (u DelegatedUserInterface) AddReaction(current types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	if err := u.checkReaction(reaction); err != nil {
+		return err
+	}
+
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.AddReaction(current, snippet, reaction)
+}
+
+func (u DelegatedUserInterface) RemoveReaction(current types.UserId, snippet types.SnippetId, reaction types.Reaction) error {
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.RemoveReaction(current, snippet, reaction)
+}
+
+// getReactableComment returns a comment the user may react to, which rules out tombstones.
+func (u DelegatedUserInterface) getReactableComment(current types.UserId, comment types.CommentId) (types.Comment, error) {
+	c, err := u.SnippetStorage.GetComment(comment)
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	if c.Deleted {
+		return types.Comment{}, NoSuchCommentErr
+	}
+
+	if _, err := u.getVisibleSnippet(current, c.Snippet); err != nil {
+		return types.Comment{}, err
+	}
+
+	return c, nil
+}
+
+func (u DelegatedUserInterface) AddCommentReaction(current types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	if err := u.checkReaction(reaction); err != nil {
+		return err
+	}
+
+	if _, err := u.getReactableComment(current, comment); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.AddCommentReaction(current, comment, reaction)
+}
+
+func (u DelegatedUserInterface) RemoveCommentReaction(current types.UserId, comment types.CommentId, reaction types.Reaction) error {
+	if _, err := u.getReactableComment(current, comment); err != nil {
+		return err
+	}
+
+	return u.SnippetStorage.RemoveCommentReaction(current, comment, reaction)
+}
+
+func (u DelegatedUserInterface) PostComment(author types.UserId, comment types.NewComment) (types.Comment, error) {
+	s, err := u.getVisibleSnippet(author, comment.Snippet)
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	depth, revision, file, lines := 0, s.Revision, comment.File, comment.Lines
+	if comment.ParentId != 0 {
+		parent, err := u.SnippetStorage.GetComment(comment.ParentId)
+		if err != nil {
+			return types.Comment{}, err
+		}
+		if parent.Snippet != comment.Snippet || parent.Deleted {
+			return types.Comment{}, NoSuchCommentErr
+		}
+		if parent.Depth >= maxCommentDepth {
+			return types.Comment{}, ReplyTooDeepErr
+		}
+		depth, revision, file, lines = parent.Depth+1, parent.Revision, parent.File, parent.Lines
+	} else {
+		files := []types.Snippet{s}
+		if err := u.fillFiles(files); err != nil {
+			return types.Comment{}, err
+		}
+		if file < 0 || file >= len(files[0].Files) {
+			return types.Comment{}, NoSuchFileErr
+		}
+		if err := lines.Validate(len(diff.SplitLines(files[0].Files[file].Contents))); err != nil {
+			return types.Comment{}, err
+		}
+	}
+
+	id, err := u.SnippetStorage.AddComment(types.Comment{
+		ParentId: comment.ParentId,
+		Depth:    depth,
+		Contents: comment.Contents,
+		Rendered: u.renderComment(comment.Contents),
+		Snippet:  comment.Snippet,
+		Revision: revision,
+		File:     file,
+		Lines:    lines,
+		Author:   author,
+	})
+
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	c, err := u.SnippetStorage.GetComment(id)
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	posted := []types.Comment{c}
+	if err := u.fillCommentVotes(author, posted); err != nil {
+		return types.Comment{}, err
+	}
+	if err := u.fillCommentReactions(author, posted); err != nil {
+		return types.Comment{}, err
+	}
+	return posted[0], nil
+}
+
+func (u DelegatedUserInterface) GetComments(current types.UserId, snippet types.SnippetId, page types.Page) (types.CommentPage, error) {
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return types.CommentPage{}, err
+	}
+
+	page = normalizePage(page)
+	c, err := u.SnippetStorage.GetComments(snippet, lookahead(page))
+	if err != nil {
+		return types.CommentPage{}, err
+	}
+
+	res := makeCommentPage(c, page)
+	if res.Comments, err = u.getThreads(current, res.Comments, page.Sort); err != nil {
+		return types.CommentPage{}, err
+	}
+	return res, nil
+}
+
+// GetLineComments groups threads on lines of the snippet by their file and last line.
+func (u DelegatedUserInterface) GetLineComments(current types.UserId, snippet types.SnippetId) ([]types.LineComments, error) {
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return []types.LineComments{}, err
+	}
+
+	c, err := u.SnippetStorage.GetLineComments(snippet)
+	if err != nil {
+		return []types.LineComments{}, err
+	}
+
+	if c, err = u.getThreads(current, c, types.SortOldest); err != nil {
+		return []types.LineComments{}, err
+	}
+
+	type fileLine struct{ file, line int }
+	byLine := make(map[fileLine][]types.Comment)
+	for _, comment := range c {
+		at := fileLine{comment.File, comment.Lines.To}
+		byLine[at] = append(byLine[at], comment)
+	}
+
+	res := make([]types.LineComments, 0, len(byLine))
+	for at, comments := range byLine {
+		res = append(res, types.LineComments{File: at.file, Line: at.line, Comments: comments})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].File != res[j].File {
+			return res[i].File < res[j].File
+		}
+		return res[i].Line < res[j].Line
+	})
+	return res, nil
+}
+
+// getThreads fills replies of top-level comments recursively. Replies go
+// oldest first, unless order is SortTop, which puts the best ones first.
+func (u DelegatedUserInterface) getThreads(current types.UserId, c []types.Comment, order types.SortOrder) ([]types.Comment, error) {
+	if len(c) == 0 {
+		return c, nil
+	}
+
+	ids := make([]types.CommentId, len(c))
+	for i := range c {
+		ids[i] = c[i].Id
+	}
+
+	replies, err := u.SnippetStorage.GetReplies(ids)
+	if err != nil {
+		return []types.Comment{}, err
+	}
+
+	if err := u.fillCommentVotes(current, c, replies); err != nil {
+		return []types.Comment{}, err
+	}
+	if err := u.fillCommentReactions(current, c, replies); err != nil {
+		return []types.Comment{}, err
+	}
+	if order == types.SortTop {
+		sort.SliceStable(replies, func(i, j int) bool {
+			return order.Before(order.CommentCursor(replies[i]), order.CommentCursor(replies[j]))
+		})
+	}
+
+	children := make(map[types.CommentId][]types.Comment)
+	for _, r := range replies {
+		children[r.ParentId] = append(children[r.ParentId], r)
+	}
+
+	var build func(c types.Comment) types.Comment
+	build = func(c types.Comment) types.Comment {
+		if c.Deleted {
+			c.Contents = DeletedCommentContents
+			c.Rendered = "<p>" + DeletedCommentContents + "</p>\n"
+			c.Author = 0
+		}
+		for _, r := range children[c.Id] {
+			c.Replies = append(c.Replies, build(r))
+		}
+		return c
+	}
+
+	for i := range c {
+		c[i] = build(c[i])
+	}
+	return c, nil
+}
+
+func (u DelegatedUserInterface) EditComment(current types.UserId, comment types.CommentId, contents string) (types.Comment, error) {
+	c, err := u.SnippetStorage.GetComment(comment)
+	if err != nil {
+		return types.Comment{}, err
+	}
+
+	if c.Deleted {
+		return types.Comment{}, NoSuchCommentErr
+	}
+
+	if c.Author != current {
+		return types.Comment{}, MustBeCommentAuthorErr
+	}
+
+	if contents != c.Contents {
+		if c, err = u.SnippetStorage.UpdateComment(comment, contents, u.renderComment(contents)); err != nil {
+			return types.Comment{}, err
+		}
+	}
+
+	edited := []types.Comment{c}
+	if err := u.fillCommentVotes(current, edited); err != nil {
+		return types.Comment{}, err
+	}
+	if err := u.fillCommentReactions(current, edited); err != nil {
+		return types.Comment{}, err
+	}
+	return edited[0], nil
+}
+
+// renderComment renders Markdown of a comment, highlighting fenced code blocks.
+func (u DelegatedUserInterface) renderComment(contents string) string {
+	return markdown.Render(contents, func(code, language string) (string, error) {
+		return u.Highlighter.HighlightCode(code, types.ProgrammingLanguage(language))
+	})
+}
+
+// GetCommentHistory lists former versions of a comment to moderators and the author.
+func (u DelegatedUserInterface) GetCommentHistory(current types.UserId, comment types.CommentId) ([]types.CommentRevision, error) {
+	c, err := u.SnippetStorage.GetComment(comment)
+	if err != nil {
+		return []types.CommentRevision{}, err
+	}
+
+	if c.Author != current && !u.Moderators[current] {
+		return []types.CommentRevision{}, MustBeModeratorErr
+	}
+
+	return u.SnippetStorage.GetCommentHistory(comment)
+}
+
+func (u DelegatedUserInterface) DeleteComment(current types.UserId, comment types.CommentId) error {
+	c, err := u.SnippetStorage.GetComment(comment)
+	if err != nil {
+		return err
+	}
+
+	if c.Deleted {
+		return NoSuchCommentErr
+	}
+
+	if c.Author != current {
+		return MustBeCommentAuthorErr
+	}
+
+	replies, err := u.SnippetStorage.GetReplies([]types.CommentId{c.Id})
+	if err != nil {
+		return err
+	}
+	if len(replies) > 0 {
+		return u.SnippetStorage.TombstoneComment(c.Id)
+	}
+
+	if err := u.SnippetStorage.DeleteComment(c.Id); err != nil {
+		return err
+	}
+
+	// Tombstones are only there to hold replies, drop the ones left without any
+	for parentId := c.ParentId; parentId != 0; {
+		parent, err := u.SnippetStorage.GetComment(parentId)
+		if err != nil || !parent.Deleted {
+			return err
+		}
+		replies, err := u.SnippetStorage.GetReplies([]types.CommentId{parent.Id})
+		if err != nil || len(replies) > 0 {
+			return err
+		}
+		if err := u.SnippetStorage.DeleteComment(parent.Id); err != nil {
+			return err
+		}
+		parentId = parent.ParentId
+	}
+	return nil
+}
+
+func (u DelegatedUserInterface) Follow(current types.UserId, user types.UserId) error {
+	if current == user {
+		return CannotFollowSelfErr
+	}
+
+	if _, err := u.UserStorage.GetAccountById(uint(user)); err != nil {
+		return err
+	}
+
+	following, err := u.FollowStorage.IsFollowing(current, user)
+	if err != nil {
+		return err
+	}
+
+	if following {
+		return AlreadyFollowingErr
+	}
+
+	return u.FollowStorage.Follow(current, user)
+}
+
+func (u DelegatedUserInterface) Unfollow(current types.UserId, user types.UserId) error {
+	following, err := u.FollowStorage.IsFollowing(current, user)
+	if err != nil {
+		return err
+	}
+
+	if !following {
+		return NotFollowingErr
+	}
+
+	return u.FollowStorage.Unfollow(current, user)
+}
+
+func (u DelegatedUserInterface) GetFollowers(user types.UserId) ([]types.User, error) {
+	if _, err := u.UserStorage.GetAccountById(uint(user)); err != nil {
+		return []types.User{}, err
+	}
+
+	return u.FollowStorage.GetFollowers(user)
+}
+
+func (u DelegatedUserInterface) GetFollowing(user types.UserId) ([]types.User, error) {
+	if _, err := u.UserStorage.GetAccountById(uint(user)); err != nil {
+		return []types.User{}, err
+	}
+
+	return u.FollowStorage.GetFollowing(user)
+}
+
+func (u DelegatedUserInterface) AddBookmark(current types.UserId, snippet types.SnippetId) error {
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return err
+	}
+
+	return u.CollectionStorage.AddBookmark(current, snippet)
+}
+
+func (u DelegatedUserInterface) RemoveBookmark(current types.UserId, snippet types.SnippetId) error {
+	return u.CollectionStorage.RemoveBookmark(current, snippet)
+}
+
+func (u DelegatedUserInterface) GetBookmarks(current types.UserId) ([]types.Snippet, error) {
+	s, err := u.CollectionStorage.GetBookmarks(current)
+	if err != nil {
+		return []types.Snippet{}, err
+	}
+
+	if err := u.fillSnippets(current, s); err != nil {
+		return []types.Snippet{}, err
+	}
+	return s, nil
+}
+
+func (u DelegatedUserInterface) CreateCollection(owner types.UserId, collection types.NewCollection) (types.Collection, error) {
+	name, err := NormalizeCollectionName(collection.Name)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	id, err := u.CollectionStorage.AddCollection(types.Collection{
+		Owner:  owner,
+		Name:   name,
+		Public: collection.Public,
+	})
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	return u.CollectionStorage.GetCollection(id)
+}
+
+// GetCollections lists collections of the user, others only see the public ones.
+func (u DelegatedUserInterface) GetCollections(user types.UserId, current types.UserId) ([]types.Collection, error) {
+	c, err := u.CollectionStorage.GetCollections(user)
+	if err != nil {
+		return []types.Collection{}, err
+	}
+
+	var res []types.Collection
+	for _, collection := range c {
+		if collection.Public || collection.Owner == current {
+			res = append(res, collection)
+		}
+	}
+	return res, nil
+}
+
+// getVisibleCollection returns a collection if the user may see it, private
+// collections of others are reported missing just like private snippets.
+func (u DelegatedUserInterface) getVisibleCollection(current types.UserId, collection types.CollectionId) (types.Collection, error) {
+	c, err := u.CollectionStorage.GetCollection(collection)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	if !c.Public && c.Owner != current {
+		return types.Collection{}, NoSuchCollectionErr
+	}
+
+	return c, nil
+}
+
+func (u DelegatedUserInterface) getOwnCollection(current types.UserId, collection types.CollectionId) (types.Collection, error) {
+	c, err := u.getVisibleCollection(current, collection)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	if c.Owner != current {
+		return types.Collection{}, MustBeCollectionOwnerErr
+	}
+
+	return c, nil
+}
+
+func (u DelegatedUserInterface) GetCollection(current types.UserId, collection types.CollectionId) (types.Collection, error) {
+	c, err := u.getVisibleCollection(current, collection)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	if c.Snippets, err = u.CollectionStorage.GetCollectionSnippets(c.Id, current); err != nil {
+		return types.Collection{}, err
+	}
+	if err := u.fillSnippets(current, c.Snippets); err != nil {
+		return types.Collection{}, err
+	}
+	return c, nil
+}
+
+func (u DelegatedUserInterface) EditCollection(current types.UserId, collection types.CollectionId, edit types.CollectionEdit) (types.Collection, error) {
+	c, err := u.getOwnCollection(current, collection)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	if edit.Name != nil {
+		if c.Name, err = NormalizeCollectionName(*edit.Name); err != nil {
+			return types.Collection{}, err
+		}
+	}
+	if edit.Public != nil {
+		c.Public = *edit.Public
+	}
+
+	if err := u.CollectionStorage.UpdateCollection(c); err != nil {
+		return types.Collection{}, err
+	}
+
+	return u.GetCollection(current, collection)
+}
+
+func (u DelegatedUserInterface) DeleteCollection(current types.UserId, collection types.CollectionId) error {
+	if _, err := u.getOwnCollection(current, collection); err != nil {
+		return err
+	}
+
+	return u.CollectionStorage.DeleteCollection(collection)
+}
+
+func (u DelegatedUserInterface) AddToCollection(current types.UserId, collection types.CollectionId, snippet types.SnippetId) error {
+	if _, err := u.getOwnCollection(current, collection); err != nil {
+		return err
+	}
+
+	if _, err := u.getVisibleSnippet(current, snippet); err != nil {
+		return err
+	}
+
+	return u.CollectionStorage.AddToCollection(collection, snippet)
+}
+
+func (u DelegatedUserInterface) RemoveFromCollection(current types.UserId, collection types.CollectionId, snippet types.SnippetId) error {
+	if _, err := u.getOwnCollection(current, collection); err != nil {
+		return err
+	}
+
+	return u.CollectionStorage.RemoveFromCollection(collection, snippet)
+}
+
+// ReorderCollection puts snippets of the collection in the given order,
+// which must list each of them exactly once.
+func (u DelegatedUserInterface) ReorderCollection(current types.UserId, collection types.CollectionId, order []types.SnippetId) (types.Collection, error) {
+	if _, err := u.getOwnCollection(current, collection); err != nil {
+		return types.Collection{}, err
+	}
+
+	items, err := u.CollectionStorage.GetCollectionItems(collection)
+	if err != nil {
+		return types.Collection{}, err
+	}
+
+	listed := make(map[types.SnippetId]bool, len(order))
+	for _, s := range order {
+		listed[s] = true
+	}
+	if len(order) != len(items) || len(listed) != len(items) {
+		return types.Collection{}, InvalidOrderErr
+	}
+	for _, s := range items {
+		if !listed[s] {
+			return types.Collection{}, InvalidOrderErr
+		}
+	}
+
+	if err := u.CollectionStorage.ReorderCollection(collection, order); err != nil {
+		return types.Collection{}, err
 	}
 
-	return u.SnippetStorage.DeleteComment(comment)
+	return u.GetCollection(current, collection)
 }
@@ -0,0 +1,1157 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/mp-hl-2021/splinter/auth"
+	"github.com/mp-hl-2021/splinter/highlighter"
+	"github.com/mp-hl-2021/splinter/storage"
+	"github.com/mp-hl-2021/splinter/types"
+	"html"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestInterface wires the use cases to a memory storage. Highlighting
+// jobs are queued, but nothing runs them.
+func newTestInterface() *DelegatedUserInterface {
+	m := storage.NewMemory()
+	return &DelegatedUserInterface{
+		UserStorage:       m,
+		SnippetStorage:    m,
+		FollowStorage:     m,
+		SearchStorage:     m,
+		CollectionStorage: m,
+		Highlighter:       highlighter.New(m, 1024, highlighter.Native{}),
+	}
+}
+
+// newTestUser creates an account straight in the storage, skipping the
+// deliberately slow password hashing.
+func newTestUser(t *testing.T, u *DelegatedUserInterface) types.UserId {
+	t.Helper()
+	a, err := u.UserStorage.CreateAccount(auth.Credentials{Username: "user" + strconv.Itoa(int(testUsers)), Password: "-"})
+	testUsers++
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.UserId(a.Id)
+}
+
+var testUsers uint
+
+func postTestSnippet(t *testing.T, u *DelegatedUserInterface, author types.UserId, snippet types.NewSnippet) types.Snippet {
+	t.Helper()
+	if snippet.Contents == "" {
+		snippet.Contents = "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"
+	}
+	if snippet.Language == "" {
+		snippet.Language = "go"
+	}
+	s, err := u.PostSnippet(author, snippet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func snippetIds(snippets []types.Snippet) map[types.SnippetId]bool {
+	res := make(map[types.SnippetId]bool, len(snippets))
+	for _, s := range snippets {
+		res[s.Id] = true
+	}
+	return res
+}
+
+// visibilityFixture has a snippet of every visibility by author, each of them
+// tagged, titled and forked alike, so that every listing may find all three.
+type visibilityFixture struct {
+	u                         *DelegatedUserInterface
+	author, stranger          types.UserId
+	parent                    types.SnippetId
+	public, unlisted, private types.SnippetId
+}
+
+func newVisibilityFixture(t *testing.T) visibilityFixture {
+	u := newTestInterface()
+	f := visibilityFixture{u: u, author: newTestUser(t, u), stranger: newTestUser(t, u)}
+	f.parent = postTestSnippet(t, u, f.author, types.NewSnippet{Title: "parent"}).Id
+
+	ids := make(map[types.Visibility]types.SnippetId)
+	for _, v := range []types.Visibility{types.VisibilityPublic, types.VisibilityUnlisted, types.VisibilityPrivate} {
+		fork, err := u.ForkSnippet(f.author, f.parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		title, tags, contents, visibility := "visibility fixture", []string{"fixture"}, "var fixtureIdentifier = 1\n", v
+		if _, err := u.EditSnippet(f.author, fork.Id, types.SnippetEdit{
+			Title:      &title,
+			Tags:       &tags,
+			Contents:   &contents,
+			Visibility: &visibility,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		ids[v] = fork.Id
+	}
+	f.public, f.unlisted, f.private = ids[types.VisibilityPublic], ids[types.VisibilityUnlisted], ids[types.VisibilityPrivate]
+
+	if err := u.Follow(f.stranger, f.author); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestOnlyPublicSnippetsAreListedForOthers(t *testing.T) {
+	f := newVisibilityFixture(t)
+	u := f.u
+	page := types.Page{Limit: types.MaxPageLimit}
+
+	listings := map[string]func(current types.UserId) ([]types.Snippet, error){
+		"user": func(current types.UserId) ([]types.Snippet, error) {
+			p, err := u.GetSnippetsByUser(f.author, current, page)
+			return p.Snippets, err
+		},
+		"language": func(current types.UserId) ([]types.Snippet, error) {
+			p, err := u.GetSnippetsByLanguage("go", current, page)
+			return p.Snippets, err
+		},
+		"tag": func(current types.UserId) ([]types.Snippet, error) {
+			p, err := u.GetSnippetsByTag("fixture", current, page)
+			return p.Snippets, err
+		},
+		"forks": func(current types.UserId) ([]types.Snippet, error) {
+			p, err := u.GetForks(f.parent, current, page)
+			return p.Snippets, err
+		},
+		"ranking": func(current types.UserId) ([]types.Snippet, error) {
+			return u.GetRankedSnippets(current, RankingQuery{Ranking: ScoreRanking{}, Limit: types.MaxPageLimit})
+		},
+		"search": func(current types.UserId) ([]types.Snippet, error) {
+			res, err := u.SearchSnippets(current, types.SearchQuery{Text: "fixture"})
+			var s []types.Snippet
+			for _, r := range res {
+				s = append(s, r.Snippet)
+			}
+			return s, err
+		},
+		"code search": func(current types.UserId) ([]types.Snippet, error) {
+			res, err := u.SearchCode(current, types.CodeSearchQuery{Mode: types.CodeSearchIdentifier, Pattern: "fixtureIdentifier"})
+			var s []types.Snippet
+			for _, r := range res {
+				s = append(s, r.Snippet)
+			}
+			return s, err
+		},
+	}
+
+	for name, list := range listings {
+		got, err := list(f.stranger)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if found := snippetIds(got); !found[f.public] || found[f.unlisted] || found[f.private] {
+			t.Errorf("%s: others see %v, want %d only of %d, %d and %d", name, found, f.public, f.public, f.unlisted, f.private)
+		}
+
+		got, err = list(f.author)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if found := snippetIds(got); !found[f.public] || !found[f.unlisted] || !found[f.private] {
+			t.Errorf("%s: the author sees %v, want all of %d, %d and %d", name, found, f.public, f.unlisted, f.private)
+		}
+	}
+
+	feed, err := u.GetFeed(f.stranger, page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := snippetIds(feed.Snippets); !found[f.public] || found[f.unlisted] || found[f.private] {
+		t.Errorf("feed has %v, want %d only of %d, %d and %d", found, f.public, f.public, f.unlisted, f.private)
+	}
+
+	languages, err := u.GetLanguages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range languages {
+		if l.Id == "go" && l.Snippets != 2 {
+			t.Errorf("%d go snippets counted, want the parent and the public one", l.Snippets)
+		}
+	}
+}
+
+func TestPrivateSnippetsAreHiddenFromOthers(t *testing.T) {
+	f := newVisibilityFixture(t)
+	u := f.u
+
+	reads := map[string]func(current types.UserId, snippet types.SnippetId) error{
+		"snippet": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetSnippet(current, snippet)
+			return err
+		},
+		"files": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetFiles(current, snippet)
+			return err
+		},
+		"revisions": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetRevisions(current, snippet)
+			return err
+		},
+		"revision": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetRevision(current, snippet, 1)
+			return err
+		},
+		"diff": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.DiffRevisions(current, snippet, "", 1, 2)
+			return err
+		},
+		"fork": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.ForkSnippet(current, snippet)
+			return err
+		},
+		"comments": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetComments(current, snippet, types.Page{})
+			return err
+		},
+		"line comments": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.GetLineComments(current, snippet)
+			return err
+		},
+		"comment": func(current types.UserId, snippet types.SnippetId) error {
+			_, err := u.PostComment(current, types.NewComment{Snippet: snippet, Contents: "nice"})
+			return err
+		},
+		"bookmark": func(current types.UserId, snippet types.SnippetId) error {
+			return u.AddBookmark(current, snippet)
+		},
+		"vote": func(current types.UserId, snippet types.SnippetId) error {
+			return u.Vote(current, snippet, 1)
+		},
+	}
+
+	for name, read := range reads {
+		for _, snippet := range []types.SnippetId{f.public, f.unlisted} {
+			if err := read(f.stranger, snippet); err != nil {
+				t.Errorf("%s of %d: %v", name, snippet, err)
+			}
+		}
+		if err := read(f.stranger, f.private); !errors.Is(err, NoSuchSnippetErr) {
+			t.Errorf("%s of the private snippet: got %v, want %v", name, err, NoSuchSnippetErr)
+		}
+		if err := read(f.author, f.private); err != nil {
+			t.Errorf("%s of the private snippet by the author: %v", name, err)
+		}
+	}
+}
+
+func TestLanguagesAreDetectedUnlessGiven(t *testing.T) {
+	u := newTestInterface()
+	author := newTestUser(t, u)
+	code := "#!/usr/bin/env python3\nprint(1)\n"
+
+	given, err := u.PostSnippet(author, types.NewSnippet{Filename: "script.py", Contents: code, Language: "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if given.Language != "go" || given.DetectedLanguage != nil {
+		t.Errorf("posted in %q, detected %+v, want go as given and nothing detected", given.Language, given.DetectedLanguage)
+	}
+
+	detected, err := u.PostSnippet(author, types.NewSnippet{Filename: "script", Contents: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if detected.Language != "python" || detected.DetectedLanguage == nil || detected.DetectedLanguage.Language != "python" {
+		t.Errorf("posted in %q, detected %+v, want python", detected.Language, detected.DetectedLanguage)
+	}
+
+	files, err := u.PostSnippet(author, types.NewSnippet{Files: []types.File{
+		{Name: "main.go", Contents: "package main\n", Language: "go"},
+		{Name: "run.sh", Contents: "echo hi\n"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files.Files[0].DetectedLanguage != nil || files.Files[1].DetectedLanguage == nil || files.Files[1].Language != "bash" {
+		t.Errorf("files in %q detected as %+v and %q detected as %+v, want only the second detected, as bash",
+			files.Files[0].Language, files.Files[0].DetectedLanguage, files.Files[1].Language, files.Files[1].DetectedLanguage)
+	}
+}
+
+func TestOnlyAuthorsDeleteSnippets(t *testing.T) {
+	f := newVisibilityFixture(t)
+	u := f.u
+
+	if err := u.DeleteSnippet(f.stranger, f.public); !errors.Is(err, MustBeSnippetAuthorErr) {
+		t.Errorf("deleting the public snippet of another user: got %v, want %v", err, MustBeSnippetAuthorErr)
+	}
+	// Private snippets of others don't exist as far as the user can tell
+	if err := u.DeleteSnippet(f.stranger, f.private); !errors.Is(err, NoSuchSnippetErr) {
+		t.Errorf("deleting the private snippet of another user: got %v, want %v", err, NoSuchSnippetErr)
+	}
+	if err := u.DeleteSnippet(f.author, f.private); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.GetSnippet(f.author, f.private); err == nil {
+		t.Error("the deleted snippet is still there")
+	}
+}
+
+func TestSnippetsMadePrivateLeaveBookmarksAndCollections(t *testing.T) {
+	f := newVisibilityFixture(t)
+	u := f.u
+
+	collection, err := u.CreateCollection(f.stranger, types.NewCollection{Name: "saved", Public: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snippet := range []types.SnippetId{f.public, f.unlisted} {
+		if err := u.AddBookmark(f.stranger, snippet); err != nil {
+			t.Fatal(err)
+		}
+		if err := u.AddToCollection(f.stranger, collection.Id, snippet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.AddToCollection(f.stranger, collection.Id, f.private); !errors.Is(err, NoSuchSnippetErr) {
+		t.Errorf("adding the private snippet to a collection: got %v, want %v", err, NoSuchSnippetErr)
+	}
+
+	private := types.VisibilityPrivate
+	if _, err := u.EditSnippet(f.author, f.unlisted, types.SnippetEdit{Visibility: &private}); err != nil {
+		t.Fatal(err)
+	}
+
+	bookmarks, err := u.GetBookmarks(f.stranger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := snippetIds(bookmarks); !found[f.public] || found[f.unlisted] {
+		t.Errorf("bookmarks have %v, want %d but not %d", found, f.public, f.unlisted)
+	}
+	c, err := u.GetCollection(f.stranger, collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := snippetIds(c.Snippets); !found[f.public] || found[f.unlisted] {
+		t.Errorf("the collection has %v, want %d but not %d", found, f.public, f.unlisted)
+	}
+	c, err = u.GetCollection(f.author, collection.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := snippetIds(c.Snippets); !found[f.public] || !found[f.unlisted] {
+		t.Errorf("the author sees %v in the collection, want both %d and %d", found, f.public, f.unlisted)
+	}
+}
+
+func TestBurnAfterReadSnippetsAreReadOnce(t *testing.T) {
+	u := newTestInterface()
+	author, reader, late := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{BurnAfterRead: true})
+
+	for i := 0; i < 2; i++ {
+		if _, err := u.GetSnippet(author, s.Id); err != nil {
+			t.Fatalf("read %d by the author: %v", i+1, err)
+		}
+	}
+	listed, err := u.GetSnippetsByUser(author, reader, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Snippets) != 0 {
+		t.Errorf("others see %d snippets of the author, want none", len(listed.Snippets))
+	}
+
+	if _, err := u.GetSnippet(reader, s.Id); err != nil {
+		t.Fatalf("the first read: %v", err)
+	}
+	if _, err := u.GetSnippet(late, s.Id); !errors.Is(err, types.ErrSnippetExpired) {
+		t.Errorf("a later read: got %v, want %v", err, types.ErrSnippetExpired)
+	}
+	if _, err := u.GetComments(late, s.Id, types.Page{}); !errors.Is(err, types.ErrSnippetExpired) {
+		t.Errorf("comments after the read: got %v, want %v", err, types.ErrSnippetExpired)
+	}
+}
+
+func TestReactionsOfSnippetsAndComments(t *testing.T) {
+	u := newTestInterface()
+	author, other := newTestUser(t, u), newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{})
+	if s.Reactions.Counts == nil {
+		t.Error("a posted snippet has nil reaction counts, want an empty map")
+	}
+
+	c, err := u.PostComment(author, types.NewComment{Snippet: s.Id, Contents: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Reactions.Counts == nil {
+		t.Error("a posted comment has nil reaction counts, want an empty map")
+	}
+
+	if err := u.AddReaction(other, s.Id, "🍕"); !errors.Is(err, UnsupportedReactionErr) {
+		t.Errorf("an unsupported reaction: got %v, want %v", err, UnsupportedReactionErr)
+	}
+	for _, user := range []types.UserId{author, other} {
+		if err := u.AddReaction(user, s.Id, "🔥"); err != nil {
+			t.Fatal(err)
+		}
+		if err := u.AddCommentReaction(user, c.Id, "😂"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.RemoveReaction(author, s.Id, "🔥"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := u.GetSnippet(other, s.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Reactions.Counts["🔥"] != 1 || len(got.Reactions.CurrentUser) != 1 {
+		t.Errorf("the snippet has reactions %+v, want one 🔥 left by the viewer", got.Reactions)
+	}
+
+	edited, err := u.EditComment(author, c.Id, "edited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Reactions.Counts["😂"] != 2 || len(edited.Reactions.CurrentUser) != 1 {
+		t.Errorf("the edited comment has reactions %+v, want two 😂, one by the author", edited.Reactions)
+	}
+
+	if err := u.DeleteComment(author, c.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AddCommentReaction(other, c.Id, "🔥"); !errors.Is(err, NoSuchCommentErr) && !errors.Is(err, storage.NoSuchCommentErr) {
+		t.Errorf("reacting to a deleted comment: got %v, want %v", err, NoSuchCommentErr)
+	}
+}
+
+func TestEditingAndCommentingOnEveryFile(t *testing.T) {
+	u := newTestInterface()
+	author := newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{Files: []types.File{
+		{Name: "main.go", Contents: "package main\n\nfunc main() {\n\tutil()\n}\n", Language: "go"},
+		{Name: "util.go", Contents: "package main\n\nfunc util() {}\n", Language: "go"},
+	}})
+
+	files := []types.File{s.Files[0], {Name: "util.go", Contents: "package main\n\nfunc util() {\n\tprintln(\"util\")\n}\n", Language: "go"}}
+	edited, err := u.EditSnippet(author, s.Id, types.SnippetEdit{Files: &files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Revision != 2 || len(edited.Files) != 2 || edited.Files[1].Contents != files[1].Contents {
+		t.Fatalf("edited snippet is %+v, want the second file replaced in revision 2", edited)
+	}
+	contents := "package main\n"
+	if edited, err = u.EditSnippet(author, s.Id, types.SnippetEdit{Contents: &contents}); err != nil {
+		t.Fatal(err)
+	}
+	if edited.Revision != 3 || edited.Contents != contents || len(edited.Files) != 2 || edited.Files[1].Contents != files[1].Contents {
+		t.Fatalf("edited snippet is %+v, want the first file replaced and the second one kept", edited)
+	}
+
+	d, err := u.DiffRevisions(author, s.Id, "util.go", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Hunks) == 0 {
+		t.Error("util.go differs between revisions 1 and 2, but the diff is empty")
+	}
+	if d, err = u.DiffRevisions(author, s.Id, "util.go", 2, 3); err != nil || len(d.Hunks) != 0 {
+		t.Errorf("util.go is the same in revisions 2 and 3, but the diff is %v, %v", d.Hunks, err)
+	}
+	if _, err := u.DiffRevisions(author, s.Id, "missing.go", 1, 2); !errors.Is(err, NoSuchFileErr) {
+		t.Errorf("diffing a missing file fails with %v, want %v", err, NoSuchFileErr)
+	}
+
+	// The first file is a single line now, the second one has five
+	if _, err := u.PostComment(author, types.NewComment{Snippet: s.Id, Lines: types.LineRange{From: 4, To: 4}, Contents: "past the end"}); err == nil {
+		t.Error("commenting past the end of the first file succeeded")
+	}
+	if _, err := u.PostComment(author, types.NewComment{Snippet: s.Id, File: 2, Lines: types.LineRange{From: 1, To: 1}, Contents: "nowhere"}); !errors.Is(err, NoSuchFileErr) {
+		t.Errorf("commenting on a missing file fails with %v, want %v", err, NoSuchFileErr)
+	}
+	onUtil, err := u.PostComment(author, types.NewComment{Snippet: s.Id, File: 1, Lines: types.LineRange{From: 4, To: 4}, Contents: "println"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := u.PostComment(author, types.NewComment{Snippet: s.Id, ParentId: onUtil.Id, Contents: "indeed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.File != 1 || reply.Lines != onUtil.Lines {
+		t.Errorf("reply is on lines %v of file %d, want the ones of its thread", reply.Lines, reply.File)
+	}
+	if _, err := u.PostComment(author, types.NewComment{Snippet: s.Id, Lines: types.LineRange{From: 1, To: 1}, Contents: "package"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lineComments, err := u.GetLineComments(author, s.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lineComments) != 2 || lineComments[0].File != 0 || lineComments[0].Line != 1 || lineComments[1].File != 1 || lineComments[1].Line != 4 {
+		t.Errorf("line comments are %+v, want line 1 of the first file, then line 4 of the second one", lineComments)
+	}
+
+	results, err := u.SearchCode(author, types.CodeSearchQuery{Mode: types.CodeSearchRegexp, Pattern: `println\("util"\)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Fragments) == 0 {
+		t.Errorf("searching code of the second file found %+v, want the snippet with a fragment", results)
+	}
+}
+
+func TestSideBySideDiffKeepsLinesInPlace(t *testing.T) {
+	u := newTestInterface()
+	author := newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{Contents: "\n\nfoo := 1\nbar := 2\n"})
+	contents := "\n\nfoo := 1\nbar := 3\n"
+	if _, err := u.EditSnippet(author, s.Id, types.SnippetEdit{Contents: &contents}); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := u.DiffRevisions(author, s.Id, "", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := u.RenderSideBySideDiff(d)
+	// The old column of every row, blank lines render as empty cells
+	cells := regexp.MustCompile(`<tr><td class="lineno">(\d+)</td><td class="\w+"><pre>(.*?)</pre></td>`).FindAllStringSubmatch(out, -1)
+	if len(cells) == 0 {
+		t.Fatalf("no rows in %q", out)
+	}
+	text := regexp.MustCompile(`<[^>]*>`)
+	want := map[string]string{"1": "", "2": "", "3": "foo := 1", "4": "bar := 2"}
+	for _, c := range cells {
+		if got := html.UnescapeString(text.ReplaceAllString(c[2], "")); got != want[c[1]] {
+			t.Errorf("old line %s renders as %q, want %q", c[1], got, want[c[1]])
+		}
+	}
+}
+
+func postTestComment(t *testing.T, u *DelegatedUserInterface, author types.UserId, comment types.NewComment) types.Comment {
+	t.Helper()
+	if comment.Contents == "" {
+		comment.Contents = "comment"
+	}
+	c, err := u.PostComment(author, comment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRepliesAreThreaded(t *testing.T) {
+	u := newTestInterface()
+	author := newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{})
+
+	top := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "top"})
+	parent := top
+	for depth := 1; depth <= maxCommentDepth; depth++ {
+		reply := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, ParentId: parent.Id, Contents: strconv.Itoa(depth)})
+		if reply.Depth != depth || reply.ParentId != parent.Id {
+			t.Fatalf("reply at depth %d to %d, want depth %d to %d", reply.Depth, reply.ParentId, depth, parent.Id)
+		}
+		parent = reply
+	}
+	if _, err := u.PostComment(author, types.NewComment{Snippet: s.Id, ParentId: parent.Id, Contents: "too deep"}); !errors.Is(err, ReplyTooDeepErr) {
+		t.Errorf("replying at depth %d: got %v, want %v", maxCommentDepth+1, err, ReplyTooDeepErr)
+	}
+
+	other := postTestSnippet(t, u, author, types.NewSnippet{})
+	if _, err := u.PostComment(author, types.NewComment{Snippet: other.Id, ParentId: top.Id, Contents: "elsewhere"}); !errors.Is(err, NoSuchCommentErr) {
+		t.Errorf("replying to a comment on another snippet: got %v, want %v", err, NoSuchCommentErr)
+	}
+
+	page, err := u.GetComments(author, s.Id, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Comments) != 1 {
+		t.Fatalf("%d top-level comments, want 1", len(page.Comments))
+	}
+	depth := 0
+	for c := page.Comments[0]; ; c = c.Replies[0] {
+		if c.Depth != depth {
+			t.Errorf("comment %d is at depth %d, want %d", c.Id, c.Depth, depth)
+		}
+		if len(c.Replies) == 0 {
+			break
+		}
+		if len(c.Replies) != 1 {
+			t.Fatalf("comment %d has %d replies, want 1", c.Id, len(c.Replies))
+		}
+		depth++
+	}
+	if depth != maxCommentDepth {
+		t.Errorf("threads go %d replies deep, want %d", depth, maxCommentDepth)
+	}
+}
+
+func TestDeletedCommentsKeepTheirReplies(t *testing.T) {
+	u := newTestInterface()
+	author, replier := newTestUser(t, u), newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{})
+
+	top := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "top"})
+	middle := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, ParentId: top.Id, Contents: "middle"})
+	reply := postTestComment(t, u, replier, types.NewComment{Snippet: s.Id, ParentId: middle.Id, Contents: "reply"})
+
+	if err := u.DeleteComment(author, top.Id); err != nil {
+		t.Fatal(err)
+	}
+	page, err := u.GetComments(replier, s.Id, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Comments) != 1 {
+		t.Fatalf("%d top-level comments, want the tombstone", len(page.Comments))
+	}
+	tombstone := page.Comments[0]
+	if tombstone.Contents != DeletedCommentContents || tombstone.Author != 0 || !strings.Contains(tombstone.Rendered, DeletedCommentContents) {
+		t.Errorf("deleted comment shows %q by %d, want %q by nobody", tombstone.Contents, tombstone.Author, DeletedCommentContents)
+	}
+	if len(tombstone.Replies) != 1 || len(tombstone.Replies[0].Replies) != 1 || tombstone.Replies[0].Replies[0].Contents != "reply" {
+		t.Fatalf("replies of the deleted comment are lost: %+v", tombstone.Replies)
+	}
+	if _, err := u.PostComment(replier, types.NewComment{Snippet: s.Id, ParentId: top.Id, Contents: "late"}); !errors.Is(err, NoSuchCommentErr) {
+		t.Errorf("replying to the deleted comment: got %v, want %v", err, NoSuchCommentErr)
+	}
+
+	// Deleting the last reply drops the tombstones left without replies
+	if err := u.DeleteComment(author, middle.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.DeleteComment(replier, reply.Id); err != nil {
+		t.Fatal(err)
+	}
+	page, err = u.GetComments(replier, s.Id, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Comments) != 0 {
+		t.Errorf("%d comments left, want none", len(page.Comments))
+	}
+}
+
+func TestLineCommentsAreGroupedByLine(t *testing.T) {
+	u := newTestInterface()
+	author := newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{Files: []types.File{
+		{Name: "main.go", Contents: "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n", Language: "go"},
+		{Name: "run.sh", Contents: "go build\n./main\n", Language: "bash"},
+	}})
+
+	invalid := map[string]types.NewComment{
+		"no first line":        {Lines: types.LineRange{To: 1}},
+		"reversed lines":       {Lines: types.LineRange{From: 3, To: 2}},
+		"past the end":         {Lines: types.LineRange{From: 5, To: 6}},
+		"past the second file": {File: 1, Lines: types.LineRange{From: 1, To: 3}},
+	}
+	for name, c := range invalid {
+		c.Snippet, c.Contents = s.Id, name
+		if _, err := u.PostComment(author, c); !errors.Is(err, types.ErrInvalidLineRange) {
+			t.Errorf("%s: got %v, want %v", name, err, types.ErrInvalidLineRange)
+		}
+	}
+	if _, err := u.PostComment(author, types.NewComment{Snippet: s.Id, File: 2, Lines: types.LineRange{From: 1, To: 1}, Contents: "x"}); !errors.Is(err, NoSuchFileErr) {
+		t.Errorf("commenting on a missing file: got %v, want %v", err, NoSuchFileErr)
+	}
+
+	postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "whole snippet"})
+	signature := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Lines: types.LineRange{From: 3, To: 5}, Contents: "signature"})
+	body := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Lines: types.LineRange{From: 5, To: 5}, Contents: "body"})
+	first := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Lines: types.LineRange{From: 1, To: 1}, Contents: "first"})
+	script := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, File: 1, Lines: types.LineRange{From: 1, To: 2}, Contents: "script"})
+	reply := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, ParentId: script.Id, Contents: "reply"})
+	if reply.File != 1 || reply.Lines != script.Lines {
+		t.Errorf("reply is on lines %v of file %d, want those of its thread", reply.Lines, reply.File)
+	}
+
+	groups, err := u.GetLineComments(author, s.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		file, line int
+		comments   []types.CommentId
+	}{
+		{0, 1, []types.CommentId{first.Id}},
+		{0, 5, []types.CommentId{signature.Id, body.Id}},
+		{1, 2, []types.CommentId{script.Id}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("%d groups, want %d: %+v", len(groups), len(want), groups)
+	}
+	for i, w := range want {
+		g := groups[i]
+		var got []types.CommentId
+		for _, c := range g.Comments {
+			got = append(got, c.Id)
+		}
+		if g.File != w.file || g.Line != w.line || !reflect.DeepEqual(got, w.comments) {
+			t.Errorf("group %d has %v on line %d of file %d, want %v on line %d of file %d", i, got, g.Line, g.File, w.comments, w.line, w.file)
+		}
+	}
+	if replies := groups[2].Comments[0].Replies; len(replies) != 1 || replies[0].Id != reply.Id {
+		t.Errorf("the thread on the script has replies %+v, want %d", replies, reply.Id)
+	}
+}
+
+func TestCommentHistoryKeepsFormerVersions(t *testing.T) {
+	u := newTestInterface()
+	author, moderator, stranger := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+	u.Moderators = map[types.UserId]bool{moderator: true}
+	s := postTestSnippet(t, u, author, types.NewSnippet{})
+	c := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "first"})
+	if c.EditedAt != nil {
+		t.Errorf("new comment edited at %v", c.EditedAt)
+	}
+
+	for _, contents := range []string{"second", "*third*", "*third*"} {
+		edited, err := u.EditComment(author, c.Id, contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edited.Contents != contents || edited.EditedAt == nil {
+			t.Errorf("edited comment says %q, edited at %v, want %q and a time", edited.Contents, edited.EditedAt, contents)
+		}
+	}
+	if _, err := u.EditComment(stranger, c.Id, "hijacked"); !errors.Is(err, MustBeCommentAuthorErr) {
+		t.Errorf("editing a comment of another user: got %v, want %v", err, MustBeCommentAuthorErr)
+	}
+
+	for _, viewer := range []types.UserId{author, moderator} {
+		history, err := u.GetCommentHistory(viewer, c.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Saving the same contents again doesn't make a revision
+		var got []string
+		for i, r := range history {
+			got = append(got, r.Contents)
+			if i > 0 && r.CreatedAt.Before(history[i-1].CreatedAt) {
+				t.Errorf("revision %d is older than revision %d", i, i-1)
+			}
+		}
+		if want := []string{"first", "second"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("user %d sees history %q, want %q", viewer, got, want)
+		}
+	}
+	if _, err := u.GetCommentHistory(stranger, c.Id); !errors.Is(err, MustBeModeratorErr) {
+		t.Errorf("history of a comment of another user: got %v, want %v", err, MustBeModeratorErr)
+	}
+}
+
+func TestCommentsAreSortedByVotes(t *testing.T) {
+	u := newTestInterface()
+	author, first, second := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{})
+	liked := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "liked"})
+	disliked := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "disliked"})
+	best := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, Contents: "best"})
+	worseReply := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, ParentId: best.Id, Contents: "worse reply"})
+	betterReply := postTestComment(t, u, author, types.NewComment{Snippet: s.Id, ParentId: best.Id, Contents: "better reply"})
+
+	votes := []struct {
+		user    types.UserId
+		comment types.CommentId
+		vote    int
+	}{
+		{first, liked.Id, 1},
+		{first, disliked.Id, 1},
+		{first, disliked.Id, -1}, // Changing a vote replaces it
+		{first, best.Id, 1},
+		{second, best.Id, 1},
+		{second, liked.Id, -1},
+		{second, liked.Id, 0}, // Zero retracts a vote
+		{first, betterReply.Id, 1},
+	}
+	for _, v := range votes {
+		if err := u.VoteComment(v.user, v.comment, v.vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.VoteComment(first, liked.Id, 2); !errors.Is(err, InvalidVoteErr) {
+		t.Errorf("voting 2: got %v, want %v", err, InvalidVoteErr)
+	}
+
+	tests := []struct {
+		sort    string
+		want    []types.CommentId
+		replies []types.CommentId
+	}{
+		{"top", []types.CommentId{best.Id, liked.Id, disliked.Id}, []types.CommentId{betterReply.Id, worseReply.Id}},
+		{"new", []types.CommentId{best.Id, disliked.Id, liked.Id}, []types.CommentId{worseReply.Id, betterReply.Id}},
+		{"old", []types.CommentId{liked.Id, disliked.Id, best.Id}, []types.CommentId{worseReply.Id, betterReply.Id}},
+	}
+	for _, test := range tests {
+		sort, err := types.ParseSortOrder(test.sort)
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := u.GetComments(first, s.Id, types.Page{Sort: sort})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, replies []types.CommentId
+		for _, c := range page.Comments {
+			got = append(got, c.Id)
+			if c.Id == best.Id {
+				for _, r := range c.Replies {
+					replies = append(replies, r.Id)
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) || !reflect.DeepEqual(replies, test.replies) {
+			t.Errorf("sort=%s: comments %v with replies %v, want %v with replies %v", test.sort, got, replies, test.want, test.replies)
+		}
+		for _, c := range page.Comments {
+			want := map[types.CommentId]int{liked.Id: 1, disliked.Id: -1, best.Id: 1}[c.Id]
+			if c.CurrentUserVote != want {
+				t.Errorf("sort=%s: vote on comment %d is %d, want %d", test.sort, c.Id, c.CurrentUserVote, want)
+			}
+		}
+	}
+}
+
+func userIds(users []types.User) []types.UserId {
+	var res []types.UserId
+	for _, u := range users {
+		res = append(res, u.Id)
+	}
+	return res
+}
+
+func TestFollowing(t *testing.T) {
+	u := newTestInterface()
+	alice, bob, carol := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+
+	if err := u.Follow(alice, alice); !errors.Is(err, CannotFollowSelfErr) {
+		t.Errorf("following yourself: got %v, want %v", err, CannotFollowSelfErr)
+	}
+	for _, f := range [][2]types.UserId{{alice, bob}, {carol, bob}, {bob, alice}} {
+		if err := u.Follow(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := u.Follow(alice, bob); !errors.Is(err, AlreadyFollowingErr) {
+		t.Errorf("following twice: got %v, want %v", err, AlreadyFollowingErr)
+	}
+	if err := u.Follow(alice, types.UserId(1<<20)); err == nil {
+		t.Error("followed a missing user")
+	}
+
+	check := func(name string, list func(types.UserId) ([]types.User, error), user types.UserId, want ...types.UserId) {
+		t.Helper()
+		users, err := list(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[types.UserId]bool)
+		for _, id := range userIds(users) {
+			got[id] = true
+		}
+		if len(got) != len(want) || len(users) != len(want) {
+			t.Errorf("%s of %d are %v, want %v", name, user, userIds(users), want)
+			return
+		}
+		for _, id := range want {
+			if !got[id] {
+				t.Errorf("%s of %d are %v, want %v", name, user, userIds(users), want)
+			}
+		}
+	}
+	check("followers", u.GetFollowers, bob, alice, carol)
+	check("following", u.GetFollowing, alice, bob)
+	check("followers", u.GetFollowers, alice, bob)
+	check("following", u.GetFollowing, carol, bob)
+
+	if err := u.Unfollow(alice, bob); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Unfollow(alice, bob); !errors.Is(err, NotFollowingErr) {
+		t.Errorf("unfollowing twice: got %v, want %v", err, NotFollowingErr)
+	}
+	check("followers", u.GetFollowers, bob, carol)
+	check("following", u.GetFollowing, alice)
+}
+
+func TestFeedHasPublicSnippetsOfFollowees(t *testing.T) {
+	u := newTestInterface()
+	reader, followee, other := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+	if err := u.Follow(reader, followee); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []types.SnippetId
+	for i := 0; i < 3; i++ {
+		want = append([]types.SnippetId{postTestSnippet(t, u, followee, types.NewSnippet{}).Id}, want...)
+	}
+	postTestSnippet(t, u, followee, types.NewSnippet{Visibility: types.VisibilityUnlisted})
+	postTestSnippet(t, u, followee, types.NewSnippet{Visibility: types.VisibilityPrivate})
+	postTestSnippet(t, u, followee, types.NewSnippet{BurnAfterRead: true})
+	postTestSnippet(t, u, other, types.NewSnippet{})
+	postTestSnippet(t, u, reader, types.NewSnippet{})
+
+	var got []types.SnippetId
+	page := types.Page{Limit: 2}
+	for pages := 0; pages <= len(want); pages++ {
+		feed, err := u.GetFeed(reader, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range feed.Snippets {
+			got = append(got, s.Id)
+		}
+		if feed.NextCursor == "" {
+			break
+		}
+		if page.After, err = types.ParseCursor(feed.NextCursor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("feed has %v, want %v, newest first", got, want)
+	}
+
+	if err := u.Unfollow(reader, followee); err != nil {
+		t.Fatal(err)
+	}
+	feed, err := u.GetFeed(reader, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Snippets) != 0 {
+		t.Errorf("feed has %v after unfollowing", snippetIds(feed.Snippets))
+	}
+}
+
+func TestEditsOfContentsAreNumberedRevisions(t *testing.T) {
+	u := newTestInterface()
+	author, stranger := newTestUser(t, u), newTestUser(t, u)
+	s := postTestSnippet(t, u, author, types.NewSnippet{Contents: "v1\n"})
+	if s.Revision != 1 {
+		t.Errorf("new snippet at revision %d, want 1", s.Revision)
+	}
+
+	title, second, third := "renamed", "v2\n", "v3\n"
+	edits := []struct {
+		name     string
+		edit     types.SnippetEdit
+		revision int
+	}{
+		{"contents", types.SnippetEdit{Contents: &second}, 2},
+		{"title", types.SnippetEdit{Title: &title}, 2},
+		{"same contents", types.SnippetEdit{Contents: &second}, 2},
+		{"contents again", types.SnippetEdit{Contents: &third}, 3},
+	}
+	for _, e := range edits {
+		edited, err := u.EditSnippet(author, s.Id, e.edit)
+		if err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		if edited.Revision != e.revision {
+			t.Errorf("%s: revision %d, want %d", e.name, edited.Revision, e.revision)
+		}
+	}
+	if _, err := u.EditSnippet(stranger, s.Id, types.SnippetEdit{Contents: &second}); !errors.Is(err, MustBeSnippetAuthorErr) {
+		t.Errorf("editing a snippet of another user: got %v, want %v", err, MustBeSnippetAuthorErr)
+	}
+
+	revisions, err := u.GetRevisions(stranger, s.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for i, r := range revisions {
+		if r.Number != i+1 || r.Snippet != s.Id {
+			t.Errorf("revision %d of snippet %d listed as number %d", r.Number, r.Snippet, i+1)
+		}
+		got = append(got, r.Contents)
+	}
+	if want := []string{"v1\n", "v2\n", "v3\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("revisions have %q, want %q", got, want)
+	}
+
+	r, err := u.GetRevision(stranger, s.Id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Number != 2 || r.Contents != second {
+		t.Errorf("revision 2 is number %d with %q, want %q", r.Number, r.Contents, second)
+	}
+	if _, err := u.GetRevision(stranger, s.Id, 4); err == nil {
+		t.Error("got revision 4 of a snippet with 3")
+	}
+}
+
+func TestForksTrackTheirParents(t *testing.T) {
+	u := newTestInterface()
+	author, forker, other := newTestUser(t, u), newTestUser(t, u), newTestUser(t, u)
+	parent := postTestSnippet(t, u, author, types.NewSnippet{Title: "parent", Tags: []string{"go"}, Files: []types.File{
+		{Name: "main.go", Contents: "package main\n", Language: "go"},
+		{Name: "run.sh", Contents: "go run .\n", Language: "bash"},
+	}})
+
+	fork, err := u.ForkSnippet(forker, parent.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork.ForkedFrom != parent.Id || fork.Author != forker || fork.Id == parent.Id {
+		t.Errorf("fork %d by %d forked from %d, want a new snippet by %d forked from %d", fork.Id, fork.Author, fork.ForkedFrom, forker, parent.Id)
+	}
+	if fork.Title != parent.Title || !reflect.DeepEqual(fork.Tags, parent.Tags) || len(fork.Files) != 2 || fork.Files[1].Contents != "go run .\n" {
+		t.Errorf("fork %+v doesn't copy its parent %+v", fork, parent)
+	}
+	grandchild, err := u.ForkSnippet(other, fork.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grandchild.ForkedFrom != fork.Id {
+		t.Errorf("fork of a fork forked from %d, want %d", grandchild.ForkedFrom, fork.Id)
+	}
+	sibling, err := u.ForkSnippet(author, parent.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Editing the parent leaves the forks as they were
+	contents := "package changed\n"
+	if _, err := u.EditSnippet(author, parent.Id, types.SnippetEdit{Contents: &contents}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := u.GetSnippet(forker, fork.Id); err != nil || got.Contents != "package main\n" {
+		t.Errorf("fork has %q, %v after editing the parent", got.Contents, err)
+	}
+
+	forks, err := u.GetForks(parent.Id, other, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snippetIds(forks.Snippets); len(got) != 2 || !got[fork.Id] || !got[sibling.Id] {
+		t.Errorf("forks of the parent are %v, want %d and %d", got, fork.Id, sibling.Id)
+	}
+	forks, err = u.GetForks(fork.Id, other, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snippetIds(forks.Snippets); len(got) != 1 || !got[grandchild.Id] {
+		t.Errorf("forks of the fork are %v, want %d", got, grandchild.Id)
+	}
+}
+
+func TestBrowsingByTags(t *testing.T) {
+	u := newTestInterface()
+	author, viewer := newTestUser(t, u), newTestUser(t, u)
+	ub := postTestSnippet(t, u, author, types.NewSnippet{Tags: []string{"Undefined Behavior", "C++"}})
+	if want := []string{"undefined-behavior", "c++"}; !reflect.DeepEqual(ub.Tags, want) {
+		t.Errorf("posted with tags %q, want %q", ub.Tags, want)
+	}
+	both := postTestSnippet(t, u, author, types.NewSnippet{Tags: []string{"undefined-behavior", "c"}})
+	postTestSnippet(t, u, author, types.NewSnippet{Tags: []string{"undefined-behavior"}, Visibility: types.VisibilityPrivate})
+	postTestSnippet(t, u, author, types.NewSnippet{Tags: []string{"c++"}})
+	if _, err := u.PostSnippet(author, types.NewSnippet{Contents: "x", Language: "go", Tags: []string{"no/slashes"}}); !errors.Is(err, InvalidTagErr) {
+		t.Errorf("posting with an invalid tag: got %v, want %v", err, InvalidTagErr)
+	}
+
+	page, err := u.GetSnippetsByTag("UNDEFINED behavior", viewer, types.Page{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := snippetIds(page.Snippets); len(got) != 2 || !got[ub.Id] || !got[both.Id] {
+		t.Errorf("snippets tagged undefined-behavior are %v, want %d and %d", got, ub.Id, both.Id)
+	}
+	if _, err := u.GetSnippetsByTag("  ", viewer, types.Page{}); !errors.Is(err, InvalidTagErr) {
+		t.Errorf("browsing an empty tag: got %v, want %v", err, InvalidTagErr)
+	}
+
+	tags, err := u.GetPopularTags(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.TagCount{{Tag: "c++", Snippets: 2}, {Tag: "undefined-behavior", Snippets: 2}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("popular tags are %+v, want %+v", tags, want)
+	}
+}
+
+func TestCollectionsKeepTheOwnersOrder(t *testing.T) {
+	u := newTestInterface()
+	owner, stranger := newTestUser(t, u), newTestUser(t, u)
+	collection, err := u.CreateCollection(owner, types.NewCollection{Name: "favorites", Public: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a, b, c, d types.SnippetId
+	for _, id := range []*types.SnippetId{&a, &b, &c, &d} {
+		*id = postTestSnippet(t, u, owner, types.NewSnippet{}).Id
+	}
+	for _, id := range []types.SnippetId{a, b, c} {
+		if err := u.AddToCollection(owner, collection.Id, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order := func(want ...types.SnippetId) {
+		t.Helper()
+		got, err := u.GetCollection(stranger, collection.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []types.SnippetId
+		for _, s := range got.Snippets {
+			ids = append(ids, s.Id)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("collection lists %v, want %v", ids, want)
+		}
+	}
+	order(a, b, c)
+
+	reordered, err := u.ReorderCollection(owner, collection.Id, []types.SnippetId{c, a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reordered.Snippets) != 3 || reordered.Snippets[0].Id != c {
+		t.Errorf("reordered collection lists %v", reordered.Snippets)
+	}
+	order(c, a, b)
+
+	invalid := map[string][]types.SnippetId{
+		"missing":   {c, a},
+		"duplicate": {c, a, a},
+		"repeated":  {c, a, b, b},
+		"foreign":   {c, a, d},
+		"extra":     {c, a, b, d},
+		"empty":     nil,
+	}
+	for name, o := range invalid {
+		if _, err := u.ReorderCollection(owner, collection.Id, o); !errors.Is(err, InvalidOrderErr) {
+			t.Errorf("%s: got %v, want %v", name, err, InvalidOrderErr)
+		}
+	}
+	if _, err := u.ReorderCollection(stranger, collection.Id, []types.SnippetId{a, b, c}); !errors.Is(err, MustBeCollectionOwnerErr) {
+		t.Errorf("reordering a collection of another user: got %v, want %v", err, MustBeCollectionOwnerErr)
+	}
+	order(c, a, b)
+
+	// Removed snippets leave the rest in order, added ones go last
+	if err := u.RemoveFromCollection(owner, collection.Id, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.AddToCollection(owner, collection.Id, d); err != nil {
+		t.Fatal(err)
+	}
+	order(c, b, d)
+}
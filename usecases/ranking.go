@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"github.com/mp-hl-2021/splinter/types"
+	"math"
+	"time"
+)
+
+// RankingStrategy scores snippets for popularity listings, higher scores go first.
+type RankingStrategy interface {
+	Score(snippet types.Snippet, now time.Time) float64
+}
+
+// ScoreRanking ranks snippets by raw likes minus dislikes.
+type ScoreRanking struct{}
+
+// WilsonRanking ranks snippets by the lower bound of the Wilson score
+// confidence interval for the share of likes, so that a few lucky votes
+// don't outrank a long track record.
+type WilsonRanking struct {
+	Z float64 // Quantile of the normal distribution, 1.96 for 95% confidence
+}
+
+// HotRanking ranks snippets by the order of magnitude of their score,
+// decayed by age: every Decay a snippet needs ten times more net likes
+// to keep its position.
+type HotRanking struct {
+	Decay time.Duration
+}
+
+var RankingStrategies = map[string]RankingStrategy{
+	"score":  ScoreRanking{},
+	"wilson": WilsonRanking{Z: 1.96},
+	"hot":    HotRanking{Decay: 12 * time.Hour},
+}
+
+type RankingQuery struct {
+	Ranking  RankingStrategy
+	Language types.ProgrammingLanguage // Empty matches any language
+	Window   time.Duration             // Zero matches snippets of any age
+	Limit    int
+}
+
+func (ScoreRanking) Score(snippet types.Snippet, _ time.Time) float64 {
+	return float64(snippet.Rating.Likes - snippet.Rating.Dislikes)
+}
+
+func (r WilsonRanking) Score(snippet types.Snippet, _ time.Time) float64 {
+	n := float64(snippet.Rating.Likes + snippet.Rating.Dislikes)
+	if n == 0 {
+		return 0
+	}
+	p := float64(snippet.Rating.Likes) / n
+	z2 := r.Z * r.Z
+	return (p + z2/(2*n) - r.Z*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
+
+func (r HotRanking) Score(snippet types.Snippet, now time.Time) float64 {
+	score := float64(snippet.Rating.Likes - snippet.Rating.Dislikes)
+	order := math.Log10(math.Max(math.Abs(score), 1))
+	if score < 0 {
+		order = -order
+	}
+	return order - float64(now.Sub(snippet.CreatedAt))/float64(r.Decay)
+}
@@ -7,14 +7,56 @@ type UserInterface interface {
 	Authenticate(username, password string) (types.Token, error)
 	GetUser(user types.UserId) (types.User, error)
 
-	PostSnippet(author types.UserId, contents string, language types.ProgrammingLanguage) (types.Snippet, error)
-	GetSnippetsByUser(user types.UserId, current types.UserId) ([]types.Snippet, error)
-	GetSnippetsByLanguage(language types.ProgrammingLanguage, current types.UserId) ([]types.Snippet, error)
+	PostSnippet(author types.UserId, snippet types.NewSnippet) (types.Snippet, error)
+	ForkSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error)
+	GetForks(snippet types.SnippetId, current types.UserId, page types.Page) (types.SnippetPage, error)
+	GetSnippetsByTag(tag string, current types.UserId, page types.Page) (types.SnippetPage, error)
+	GetPopularTags(limit int) ([]types.TagCount, error)
+	GetSnippetsByUser(user types.UserId, current types.UserId, page types.Page) (types.SnippetPage, error)
+	GetLanguages() ([]types.LanguageCount, error)
+	GetSnippetsByLanguage(language types.ProgrammingLanguage, current types.UserId, page types.Page) (types.SnippetPage, error)
+	GetFeed(current types.UserId, page types.Page) (types.SnippetPage, error)
+	GetRankedSnippets(current types.UserId, query RankingQuery) ([]types.Snippet, error)
+	SearchSnippets(current types.UserId, query types.SearchQuery) ([]types.SearchResult, error)
+	SearchCode(current types.UserId, query types.CodeSearchQuery) ([]types.SearchResult, error)
 	GetSnippet(current types.UserId, snippet types.SnippetId) (types.Snippet, error)
+	GetFiles(current types.UserId, snippet types.SnippetId) ([]types.File, error)
+	GetFile(current types.UserId, snippet types.SnippetId, name string) (types.File, error)
+	EditSnippet(current types.UserId, snippet types.SnippetId, edit types.SnippetEdit) (types.Snippet, error)
+	GetRevisions(current types.UserId, snippet types.SnippetId) ([]types.Revision, error)
+	GetRevision(current types.UserId, snippet types.SnippetId, revision int) (types.Revision, error)
+	DiffRevisions(current types.UserId, snippet types.SnippetId, file string, from, to int) (types.RevisionDiff, error)
+	RenderSideBySideDiff(d types.RevisionDiff) string
 	DeleteSnippet(current types.UserId, snippet types.SnippetId) error
 	Vote(current types.UserId, snippet types.SnippetId, vote int /* ±1 */) error
+	GetReactions() []types.Reaction
+	AddReaction(current types.UserId, snippet types.SnippetId, reaction types.Reaction) error
+	RemoveReaction(current types.UserId, snippet types.SnippetId, reaction types.Reaction) error
 
-	PostComment(author types.UserId, contents string, snippet types.SnippetId) (types.Comment, error)
-	GetComments(snippet types.SnippetId) ([]types.Comment, error)
+	PostComment(author types.UserId, comment types.NewComment) (types.Comment, error)
+	GetComments(current types.UserId, snippet types.SnippetId, page types.Page) (types.CommentPage, error)
+	GetLineComments(current types.UserId, snippet types.SnippetId) ([]types.LineComments, error)
+	EditComment(current types.UserId, comment types.CommentId, contents string) (types.Comment, error)
+	GetCommentHistory(current types.UserId, comment types.CommentId) ([]types.CommentRevision, error)
 	DeleteComment(current types.UserId, comment types.CommentId) error
+	VoteComment(current types.UserId, comment types.CommentId, vote int /* ±1 */) error
+	AddCommentReaction(current types.UserId, comment types.CommentId, reaction types.Reaction) error
+	RemoveCommentReaction(current types.UserId, comment types.CommentId, reaction types.Reaction) error
+
+	Follow(current types.UserId, user types.UserId) error
+	Unfollow(current types.UserId, user types.UserId) error
+	GetFollowers(user types.UserId) ([]types.User, error)
+	GetFollowing(user types.UserId) ([]types.User, error)
+
+	AddBookmark(current types.UserId, snippet types.SnippetId) error
+	RemoveBookmark(current types.UserId, snippet types.SnippetId) error
+	GetBookmarks(current types.UserId) ([]types.Snippet, error)
+	CreateCollection(owner types.UserId, collection types.NewCollection) (types.Collection, error)
+	GetCollections(user types.UserId, current types.UserId) ([]types.Collection, error)
+	GetCollection(current types.UserId, collection types.CollectionId) (types.Collection, error)
+	EditCollection(current types.UserId, collection types.CollectionId, edit types.CollectionEdit) (types.Collection, error)
+	DeleteCollection(current types.UserId, collection types.CollectionId) error
+	AddToCollection(current types.UserId, collection types.CollectionId, snippet types.SnippetId) error
+	RemoveFromCollection(current types.UserId, collection types.CollectionId, snippet types.SnippetId) error
+	ReorderCollection(current types.UserId, collection types.CollectionId, order []types.SnippetId) (types.Collection, error)
 }
@@ -0,0 +1,136 @@
+package usecases
+
+import (
+	"errors"
+	"github.com/mp-hl-2021/splinter/languages"
+	"github.com/mp-hl-2021/splinter/types"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	TitleTooLongErr       = errors.New("title is too long")
+	DescriptionTooLongErr = errors.New("description is too long")
+	TooManyTagsErr        = errors.New("too many tags")
+	InvalidTagErr         = errors.New("tag contains invalid character")
+	TagTooLongErr         = errors.New("tag is too long")
+	InvalidTTLErr         = errors.New("ttl must be positive and at most a year")
+	EmptyNameErr          = errors.New("name is empty")
+	NameTooLongErr        = errors.New("name is too long")
+	TooManyFilesErr       = errors.New("too many files")
+	InvalidFilenameErr    = errors.New("file name must be non-empty and without slashes")
+	DuplicateFilenameErr  = errors.New("file names must be unique")
+	UnknownLanguageErr    = errors.New("unknown language")
+)
+
+const (
+	maxTitleLength       = 100
+	maxDescriptionLength = 10000
+	maxTags              = 5
+	maxTagLength         = 32
+	maxTTL               = 365 * 24 * time.Hour
+	maxNameLength        = 100
+	maxFiles             = 10
+)
+
+func ValidateTitle(title string) error {
+	if len([]rune(title)) > maxTitleLength {
+		return TitleTooLongErr
+	}
+	return nil
+}
+
+func ValidateDescription(description string) error {
+	if len([]rune(description)) > maxDescriptionLength {
+		return DescriptionTooLongErr
+	}
+	return nil
+}
+
+// NormalizeCollectionName trims spaces around the name of a collection.
+func NormalizeCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", EmptyNameErr
+	}
+	if len([]rune(name)) > maxNameLength {
+		return "", NameTooLongErr
+	}
+	return name, nil
+}
+
+// ValidateFiles checks files of a snippet. The only file of a single-file
+// snippet may go without a name, otherwise every file needs a distinct one.
+func ValidateFiles(files []types.File) error {
+	if len(files) > maxFiles {
+		return TooManyFilesErr
+	}
+
+	names := make(map[string]bool, len(files))
+	for _, f := range files {
+		if f.Name == "" && len(files) == 1 {
+			continue
+		}
+		if strings.TrimSpace(f.Name) == "" || strings.ContainsAny(f.Name, "/\\\x00") {
+			return InvalidFilenameErr
+		}
+		if len([]rune(f.Name)) > maxNameLength {
+			return NameTooLongErr
+		}
+		if names[f.Name] {
+			return DuplicateFilenameErr
+		}
+		names[f.Name] = true
+	}
+	return nil
+}
+
+// NormalizeLanguage maps a language name or an alias such as "golang" or "C++"
+// to the identifier of the language in the registry. Empty stays empty.
+func NormalizeLanguage(language types.ProgrammingLanguage) (types.ProgrammingLanguage, error) {
+	if language == "" {
+		return "", nil
+	}
+	l, ok := languages.Lookup(string(language))
+	if !ok {
+		return "", UnknownLanguageErr
+	}
+	return l.Id, nil
+}
+
+// ValidateTTL accepts zero for snippets that never expire.
+func ValidateTTL(ttl time.Duration) error {
+	if ttl < 0 || ttl > maxTTL {
+		return InvalidTTLErr
+	}
+	return nil
+}
+
+// NormalizeTags lowercases tags, joins words with dashes and drops
+// empty tags and duplicates, so that "Undefined Behavior" and
+// "undefined-behavior" end up being the same tag.
+func NormalizeTags(tags []string) ([]string, error) {
+	var res []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-+#.", r) {
+				return nil, InvalidTagErr
+			}
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return nil, TagTooLongErr
+		}
+		seen[tag] = true
+		res = append(res, tag)
+	}
+	if len(res) > maxTags {
+		return nil, TooManyTagsErr
+	}
+	return res, nil
+}
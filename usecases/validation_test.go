@@ -0,0 +1,34 @@
+package usecases
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want []string
+		err  error
+	}{
+		{nil, nil, nil},
+		{[]string{"Go"}, []string{"go"}, nil},
+		{[]string{"Undefined Behavior", "undefined-behavior", "  UNDEFINED   behavior "}, []string{"undefined-behavior"}, nil},
+		{[]string{"c++", "c#", "node.js", "утилиты"}, []string{"c++", "c#", "node.js", "утилиты"}, nil},
+		{[]string{"", "  ", "x"}, []string{"x"}, nil},
+		{[]string{"a", "b", "c", "d", "e", "A", "B"}, []string{"a", "b", "c", "d", "e"}, nil},
+		{[]string{"a", "b", "c", "d", "e", "f"}, nil, TooManyTagsErr},
+		{[]string{"<script>"}, nil, InvalidTagErr},
+		{[]string{"a/b"}, nil, InvalidTagErr},
+		{[]string{strings.Repeat("я", maxTagLength)}, []string{strings.Repeat("я", maxTagLength)}, nil},
+		{[]string{strings.Repeat("x", maxTagLength+1)}, nil, TagTooLongErr},
+	}
+	for _, test := range tests {
+		got, err := NormalizeTags(test.tags)
+		if !errors.Is(err, test.err) || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("NormalizeTags(%q) = %q, %v, want %q, %v", test.tags, got, err, test.want, test.err)
+		}
+	}
+}